@@ -4,7 +4,9 @@ import (
 	"aww/internal/backend"
 	"aww/internal/repository"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/fatih/color"
 	"github.com/rs/zerolog/log"
@@ -12,7 +14,7 @@ import (
 )
 
 // run is action for run command
-func run(project *repository.Project, groupActions *repository.GroupActions) error {
+func run(ctx context.Context, out io.Writer, project *repository.Project, groupActions *repository.GroupActions) error {
 	projectPath := project.GetPath()
 	if project.Actions == nil && groupActions == nil {
 		return nil
@@ -43,7 +45,7 @@ func run(project *repository.Project, groupActions *repository.GroupActions) err
 
 	if performCommit {
 		// Check for changes
-		ok, err := ifUncomitted(projectPath)
+		ok, err := ifUncomitted(ctx, projectPath)
 		if err != nil {
 			return fmt.Errorf("checking if uncommitted failed for %s: %w", projectPath, err)
 		}
@@ -55,27 +57,35 @@ func run(project *repository.Project, groupActions *repository.GroupActions) err
 
 		// Perform commit
 		log.Debug().Str("path", projectPath).Str("message", commitMsg).Msg("Performing commit...")
-		err = backend.Git.Add(&backend.Options{
-			Dir: projectPath,
+		err = Backend.Add(&backend.Options{
+			Context: ctx,
+			Dir:     projectPath,
 		})
 		if err != nil {
 			return fmt.Errorf("add failed for %s: %w", projectPath, err)
 		}
 
-		err = backend.Git.Commit(&backend.Options{
+		err = Backend.Commit(&backend.Options{
+			Context:   ctx,
 			Dir:       projectPath,
 			CommitMsg: commitMsg,
 		})
 		if err != nil {
 			return fmt.Errorf("commit failed for %s: %w", projectPath, err)
 		}
+		// The repository changed, collect its state again for the push
+		forgetState(projectPath)
 		log.Info().Str("path", projectPath).Str("commitMsg", commitMsg).Msg("Commit successful")
 	}
 
 	if performPush {
 		// Perform push
 		log.Debug().Str("path", projectPath).Msg("Performing push...")
-		ok, err := ifUnpushed(projectPath)
+		ok, err := ifUnpushed(ctx, projectPath)
+		if errors.Is(err, errNoUpstream) {
+			log.Warn().Str("path", projectPath).Msg("No upstream configured, skipping push")
+			return nil
+		}
 		if err != nil {
 			return fmt.Errorf("push failed for %s: %w", projectPath, err)
 		}
@@ -83,7 +93,7 @@ func run(project *repository.Project, groupActions *repository.GroupActions) err
 			log.Info().Str("path", projectPath).Msg("No commits to push found")
 			return nil
 		}
-		err = backend.Git.Push(&backend.Options{Dir: projectPath})
+		err = Backend.Push(&backend.Options{Context: ctx, Dir: projectPath, Remote: Remote})
 		if err != nil {
 			return fmt.Errorf("push failed for %s: %w", projectPath, err)
 		}
@@ -94,7 +104,7 @@ func run(project *repository.Project, groupActions *repository.GroupActions) err
 }
 
 // plan is action for plan command
-func plan(project *repository.Project, groupActions *repository.GroupActions) error {
+func plan(ctx context.Context, out io.Writer, project *repository.Project, groupActions *repository.GroupActions) error {
 	projectPath := project.GetPath()
 	if project.Actions == nil && groupActions == nil {
 		return nil
@@ -123,12 +133,12 @@ func plan(project *repository.Project, groupActions *repository.GroupActions) er
 		return nil
 	}
 
-	var commitPerformed, pushPerformed bool
+	var commitPerformed, pushPerformed, noUpstream bool
 	var outputBuffer string
 
 	if performCommit {
 		// Check for changes
-		ok, err := ifUncomitted(projectPath)
+		ok, err := ifUncomitted(ctx, projectPath)
 		if err != nil {
 			return fmt.Errorf("checking if uncommitted failed for %s: %w", projectPath, err)
 		}
@@ -138,8 +148,11 @@ func plan(project *repository.Project, groupActions *repository.GroupActions) er
 	}
 
 	if performPush {
-		ok, err := ifUnpushed(projectPath)
-		if err != nil {
+		ok, err := ifUnpushed(ctx, projectPath)
+		if errors.Is(err, errNoUpstream) {
+			log.Warn().Str("path", projectPath).Msg("No upstream configured, the push would be skipped")
+			noUpstream = true
+		} else if err != nil {
 			return fmt.Errorf("push failed for %s: %w", projectPath, err)
 		}
 		if ok {
@@ -164,6 +177,8 @@ func plan(project *repository.Project, groupActions *repository.GroupActions) er
 	if performPush {
 		if pushPerformed {
 			outputBuffer += fmt.Sprintf("    └── Push: %s\n", success("true"))
+		} else if noUpstream {
+			outputBuffer += fmt.Sprintf("    └── Push: %s\n", failure("No upstream configured"))
 		} else {
 			outputBuffer += fmt.Sprintf("    └── Push: %s\n", failure("false"))
 		}
@@ -172,28 +187,19 @@ func plan(project *repository.Project, groupActions *repository.GroupActions) er
 	}
 
 	// Print the buffered output
-	fmt.Print(outputBuffer)
+	fmt.Fprint(out, outputBuffer)
 
 	return nil
 }
 
-// reset is action for reset command
-func reset(project *repository.Project, groupActions *repository.GroupActions) error {
-	if project.Actions == nil && groupActions == nil {
-		// No actions defined at both levels, nothing to reset
-		return nil
-	}
-
+// reset is action for reset command.
+// Group-level actions are shared by concurrently processed projects, so they are reset by the command itself.
+func reset(ctx context.Context, out io.Writer, project *repository.Project, groupActions *repository.GroupActions) error {
 	if project.Actions != nil {
 		// Reset project-specific actions
 		project.Actions.Reset()
 	}
 
-	if groupActions != nil {
-		// Reset group-level actions
-		groupActions.Reset()
-	}
-
 	return nil
 }
 
@@ -201,41 +207,30 @@ func Actions() *cli.Command {
 	return &cli.Command{
 		Name:  "actions",
 		Usage: "Perform actions on groups and repositories",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:    "repo",
-				Aliases: []string{"r"},
-				Usage:   "Operate only on the specified group name",
-			},
-		},
+		Flags: selectionFlags(),
 		Commands: []*cli.Command{
 			{
 				Name:  "apply",
 				Usage: "Run actions specified in the configuration",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
-					err := start()
+					unlock, err := startForUpdate()
 					if err != nil {
 						return err
 					}
+					defer unlock()
 
 					err = overrideGroups(cmd)
 					if err != nil {
 						return err
 					}
 
-					for _, group := range groups {
-						if len(group.Projects) == 0 {
-							log.Warn().Str("group", group.Name).Msg("Doesn't contain any projects")
-							continue
-						}
-						// Execute the provided action
-						err = processProjects(group.Projects, group.Actions, run)
-						if err != nil {
-							return err
-						}
+					// Execute the provided action
+					err = processProjects(ctx, groups, run)
+					if err != nil {
+						return err
 					}
 
-					err = repository.Save(groups)
+					err = repository.Save(allGroups)
 					if err != nil {
 						return err
 					}
@@ -247,30 +242,31 @@ func Actions() *cli.Command {
 				Name:  "reset",
 				Usage: "Reset configuration",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
-					err := start()
+					unlock, err := startForUpdate()
 					if err != nil {
 						return err
 					}
+					defer unlock()
 
 					err = overrideGroups(cmd)
 					if err != nil {
 						return err
 					}
 
-					for _, group := range groups {
-						if len(group.Projects) == 0 {
-							log.Warn().Str("group", group.Name).Msg("Doesn't contain any projects")
-							continue
-						}
+					// Execute the provided action
+					err = processProjects(ctx, groups, reset)
+					if err != nil {
+						return err
+					}
 
-						// Execute the provided action
-						err = processProjects(group.Projects, group.Actions, reset)
-						if err != nil {
-							return err
+					for _, group := range groups {
+						if group.Actions != nil {
+							// Reset group-level actions
+							group.Actions.Reset()
 						}
 					}
 
-					err = repository.Save(groups)
+					err = repository.Save(allGroups)
 					if err != nil {
 						return err
 					}
@@ -292,16 +288,10 @@ func Actions() *cli.Command {
 						return err
 					}
 
-					for _, group := range groups {
-						if len(group.Projects) == 0 {
-							log.Warn().Str("group", group.Name).Msg("Doesn't contain any projects")
-							continue
-						}
-						// Execute the provided action
-						err = processProjects(group.Projects, group.Actions, plan)
-						if err != nil {
-							return err
-						}
+					// Execute the provided action
+					err = processProjects(ctx, groups, plan)
+					if err != nil {
+						return err
 					}
 					return nil
 				},
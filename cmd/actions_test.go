@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/repository"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const actionsRepositories = `version: 2
+groups:
+  - name: team
+    actions:
+      commit: update dependencies
+      push: true
+    projects:
+      - url: git@example.com:team/api.git
+      - url: git@example.com:team/web.git
+      - url: git@example.com:team/docs.git
+        actions:
+          push: false
+`
+
+// actionsRepos are the repositories of actionsRepositories, api and docs have changes
+func actionsRepos() map[string]*backend.FakeRepository {
+	return map[string]*backend.FakeRepository{
+		"example.com/team/api":  {Branch: "main", Changes: []string{"go.mod"}},
+		"example.com/team/web":  {Branch: "main"},
+		"example.com/team/docs": {Branch: "main", Changes: []string{"README.md"}},
+	}
+}
+
+func TestActionsApply(t *testing.T) {
+	repos := actionsRepos()
+	fake, _ := fakeRepositories(t, actionsRepositories, repos)
+
+	if _, err := runCommand(t, Actions(), "actions", "apply"); err != nil {
+		t.Fatalf("actions apply: %v", err)
+	}
+
+	tests := []struct {
+		path         string
+		wantPushed   []string
+		wantUnpushed []string
+	}{
+		{"example.com/team/api", []string{"update dependencies"}, nil},
+		{"example.com/team/web", nil, nil},
+		{"example.com/team/docs", nil, []string{"update dependencies"}},
+	}
+	for _, tt := range tests {
+		repo := repos[tt.path]
+		if !slices.Equal(repo.Pushed, tt.wantPushed) || !slices.Equal(repo.Unpushed, tt.wantUnpushed) {
+			t.Errorf("%s pushed %q and kept %q unpushed, want %q and %q", tt.path, repo.Pushed, repo.Unpushed, tt.wantPushed, tt.wantUnpushed)
+		}
+		if len(repo.Changes) > 0 {
+			t.Errorf("%s still has changes %q", tt.path, repo.Changes)
+		}
+	}
+
+	for _, call := range fake.Calls {
+		if strings.HasPrefix(call, "commit ") && strings.HasSuffix(call, "/web") {
+			t.Errorf("web without changes was committed")
+		}
+	}
+}
+
+func TestActionsApplyWithoutUpstream(t *testing.T) {
+	repos := actionsRepos()
+	repos["example.com/team/api"].NoUpstream = true
+	fake, _ := fakeRepositories(t, actionsRepositories, repos)
+
+	if _, err := runCommand(t, Actions(), "actions", "apply"); err != nil {
+		t.Fatalf("actions apply: %v", err)
+	}
+
+	// The commit is made but not pushed
+	if api := repos["example.com/team/api"]; len(api.Pushed) > 0 || !slices.Equal(api.Unpushed, []string{"update dependencies"}) {
+		t.Errorf("api pushed %q and kept %q unpushed, want the commit kept", api.Pushed, api.Unpushed)
+	}
+	for _, call := range fake.Calls {
+		if strings.HasPrefix(call, "push ") {
+			t.Errorf("branch without upstream was pushed: %s", call)
+		}
+	}
+
+	output, err := runCommand(t, Actions(), "actions", "plan")
+	if err != nil {
+		t.Fatalf("actions plan: %v", err)
+	}
+	if !strings.Contains(output, "Push: No upstream configured") {
+		t.Errorf("plan output doesn't report the missing upstream:\n%s", output)
+	}
+}
+
+func TestActionsPlan(t *testing.T) {
+	repos := actionsRepos()
+	fake, _ := fakeRepositories(t, actionsRepositories, repos)
+
+	output, err := runCommand(t, Actions(), "actions", "plan")
+	if err != nil {
+		t.Fatalf("actions plan: %v", err)
+	}
+
+	for _, want := range []string{"Commit: update dependencies", "Commit: No changes to commit", "Push: false"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("plan output doesn't contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "Project: ") != 3 {
+		t.Errorf("plan output doesn't show the 3 projects:\n%s", output)
+	}
+
+	// Planning doesn't change the repositories
+	for _, call := range fake.Calls {
+		if !strings.HasPrefix(call, "state ") {
+			t.Errorf("plan performed %s", call)
+		}
+	}
+	if repos["example.com/team/api"].Changes == nil {
+		t.Error("plan committed the changes of api")
+	}
+}
+
+func TestActionsReset(t *testing.T) {
+	fakeRepositories(t, actionsRepositories, actionsRepos())
+
+	if _, err := runCommand(t, Actions(), "actions", "reset"); err != nil {
+		t.Fatalf("actions reset: %v", err)
+	}
+
+	content, err := os.ReadFile(repository.MainFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "update dependencies") {
+		t.Errorf("actions weren't reset:\n%s", content)
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/cache"
+	"aww/internal/pool"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Cache creates a CLI command maintaining the shared object cache of clones
+func Cache() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "Maintain the bare mirrors sharing objects between clones (--cache)",
+		Flags: selectionFlags(),
+		Commands: []*cli.Command{
+			{
+				Name:  "update",
+				Usage: "Create the missing mirrors of the repositories and fetch the new objects of the existing ones",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					err := start()
+					if err != nil {
+						return err
+					}
+
+					err = overrideGroups(cmd)
+					if err != nil {
+						return err
+					}
+
+					tasks := collectProjects(groups)
+					errs := pool.Run(ctx, int(Jobs), len(tasks), func(ctx context.Context, i int) error {
+						url := tasks[i].project.Url
+
+						dir, err := cache.Dir(url)
+						if err != nil {
+							return fmt.Errorf("problem with decoding project %s: %v", url, err)
+						}
+						err = Backend.Mirror(&backend.Options{Context: ctx, Url: url, Dir: dir})
+						if err != nil {
+							return fmt.Errorf("failed to update mirror of %s: %w", url, err)
+						}
+
+						log.Info().Str("url", url).Str("mirror", dir).Msg("Mirror updated ✅")
+						return nil
+					})
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+
+					return errors.Join(errs...)
+				},
+			},
+			{
+				Name:  "gc",
+				Usage: "Compact the objects of the mirrors",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "unused",
+						Usage: "remove the mirrors of repositories missing in the repositories file, clones borrowing their objects break unless cloned with --dissociate",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					err := start()
+					if err != nil {
+						return err
+					}
+
+					mirrors, err := cache.Mirrors()
+					if err != nil {
+						return err
+					}
+
+					used := map[string]bool{}
+					for _, task := range collectProjects(allGroups) {
+						if dir, err := cache.Dir(task.project.Url); err == nil {
+							used[dir] = true
+						}
+					}
+
+					errs := pool.Run(ctx, int(Jobs), len(mirrors), func(ctx context.Context, i int) error {
+						dir := mirrors[i]
+
+						if !used[dir] && cmd.Bool("unused") {
+							if err := cache.Remove(dir); err != nil {
+								return err
+							}
+							log.Info().Str("mirror", dir).Msg("Unused mirror removed ✅")
+							return nil
+						}
+
+						err := Backend.GC(&backend.Options{Context: ctx, Dir: dir})
+						if err != nil {
+							return fmt.Errorf("gc failed for %s: %w", dir, err)
+						}
+						log.Info().Str("mirror", dir).Msg("Mirror compacted ✅")
+						return nil
+					})
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+
+					return errors.Join(errs...)
+				},
+			},
+		},
+	}
+}
+
+// cacheMirror updates the mirror of the repository when the cache is enabled and returns it.
+// The cache only saves space and bandwidth, failures are logged and the repository is cloned without it.
+func cacheMirror(ctx context.Context, url string) string {
+	if !UseCache {
+		return ""
+	}
+
+	dir, err := cache.Dir(url)
+	if err != nil {
+		log.Warn().Str("url", url).Err(err).Msg("Cloning without the cache")
+		return ""
+	}
+	err = Backend.Mirror(&backend.Options{Context: ctx, Url: url, Dir: dir})
+	if err != nil {
+		log.Warn().Str("url", url).Err(err).Msg("Failed to update the mirror, cloning without the cache")
+		return ""
+	}
+	return dir
+}
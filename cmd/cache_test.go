@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/cache"
+	"aww/internal/repository"
+	"context"
+	"errors"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs a git command for the test and returns its output
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+
+	output, err := osexec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestCloneThroughCache(t *testing.T) {
+	dir := t.TempDir()
+	work, bare := filepath.Join(dir, "work"), filepath.Join(dir, "remote", "team", "api.git")
+	commit := func(message string) {
+		git(t, "-C", work, "-c", "user.name=aww", "-c", "user.email=aww@example.com", "commit", "-q", "--allow-empty", "-m", message)
+	}
+	git(t, "init", "-q", "-b", "main", work)
+	commit("initial")
+	git(t, "clone", "-q", "--bare", work, bare)
+	url := "file://" + bare
+
+	cachePath, savedBackend, useCache, dissociate := cache.Path, Backend, UseCache, Dissociate
+	t.Cleanup(func() { cache.Path, Backend, UseCache, Dissociate = cachePath, savedBackend, useCache, dissociate })
+	cache.Path, Backend, UseCache = filepath.Join(dir, "cache"), backend.NewCLI(), true
+
+	// The same repository in two roots
+	clone := func(root string, dissociate bool) string {
+		t.Helper()
+
+		group := &repository.Group{Name: filepath.Base(root), Root: filepath.Join(dir, root)}
+		project := &repository.Project{Url: url}
+		group.AddProject(project)
+
+		Dissociate = dissociate
+		result, err := cloneProject(context.Background(), project, nil)
+		if err != nil {
+			t.Fatalf("cloneProject() error = %v", err)
+		}
+		if result.result != cloneCloned || !strings.Contains(result.reason, "cache") {
+			t.Fatalf("cloneProject() = %+v, want a clone through the cache", result)
+		}
+		return project.GetPath()
+	}
+	alternates := func(clone string) (string, error) {
+		content, err := os.ReadFile(filepath.Join(clone, ".git", "objects", "info", "alternates"))
+		return strings.TrimSpace(string(content)), err
+	}
+
+	mirror, err := cache.Dir(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// First clone creates the mirror and borrows its objects
+	shared := clone("shared", false)
+	if git(t, "-C", mirror, "rev-parse", "--is-bare-repository") != "true" {
+		t.Fatalf("mirror %s isn't a bare repository", mirror)
+	}
+	if objects, err := alternates(shared); err != nil || objects != filepath.Join(mirror, "objects") {
+		t.Errorf("alternates of the first clone = %q, %v, want the objects of the mirror", objects, err)
+	}
+
+	// Second clone fetches the new commits into the mirror and copies the objects
+	commit("second")
+	git(t, "-C", work, "push", "-q", bare, "main")
+	copied := clone("copied", true)
+	if _, err := alternates(copied); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dissociated clone has alternates: %v", err)
+	}
+	if head, want := git(t, "-C", mirror, "rev-parse", "main"), git(t, "-C", work, "rev-parse", "HEAD"); head != want {
+		t.Errorf("mirror is at %s, want the pushed commit %s", head, want)
+	}
+
+	// The dissociated clone doesn't depend on the cache anymore
+	if err := cache.Remove(mirror); err != nil {
+		t.Fatal(err)
+	}
+	git(t, "-C", copied, "fsck", "--no-dangling")
+	if log := git(t, "-C", copied, "log", "--format=%s"); log != "second\ninitial" {
+		t.Errorf("history of the dissociated clone = %q", log)
+	}
+}
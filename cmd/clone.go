@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/pool"
+	"aww/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chelnak/ysmrr"
+	"github.com/urfave/cli/v3"
+)
+
+// Results of cloning a single repository
+const (
+	cloneCloned  = "cloned"
+	cloneSkipped = "skipped"
+	cloneFailed  = "failed"
+)
+
+// cloneResult is the outcome of cloning a single repository
+type cloneResult struct {
+	result string
+	reason string
+}
+
+// cloneLine is the spinner of a clone worker
+type cloneLine struct {
+	spinner *ysmrr.Spinner
+	last    *cloneResult // Outcome of the last clone of the worker
+}
+
+// Clone creates a CLI command cloning the repositories which aren't on disk yet.
+// Repositories are cloned concurrently (bounded by --jobs), every clone in flight has its own
+// spinner showing the progress reported by git.
+func Clone() *cli.Command {
+	return &cli.Command{
+		Name:  "clone",
+		Usage: "Clone all repositories for the specified groups",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			err := start()
+			if err != nil {
+				return err
+			}
+
+			err = overrideGroups(cmd)
+			if err != nil {
+				return err
+			}
+
+			tasks := collectProjects(groups)
+			results := make([]*cloneResult, len(tasks))
+
+			workers := int(Jobs)
+			if workers < 1 {
+				workers = pool.DefaultJobs
+			}
+			workers = min(workers, len(tasks))
+
+			// One spinner line per worker, reused by the clones the worker runs
+			sm := ysmrr.NewSpinnerManager()
+			lines := make(chan *cloneLine, workers)
+			for range workers {
+				lines <- &cloneLine{spinner: sm.AddSpinner("waiting...")}
+			}
+			sm.Start()
+
+			errs := pool.Run(ctx, workers, len(tasks), func(ctx context.Context, i int) error {
+				line := <-lines
+				defer func() { lines <- line }()
+
+				group, project := tasks[i].group, tasks[i].project
+				name := fmt.Sprintf("[%s] %s", group.Name, project.Url)
+				line.spinner.UpdateMessagef("%s: starting", name)
+
+				result, err := cloneProject(ctx, project, func(progress backend.Progress) {
+					line.spinner.UpdateMessagef("%s: %s", name, progress)
+				})
+				results[i], line.last = result, result
+				line.spinner.UpdateMessagef("%s: %s", name, result.result)
+				return err
+			})
+
+			// Every worker is done, its line keeps the outcome of its last clone
+			close(lines)
+			for line := range lines {
+				if line.last != nil && line.last.result == cloneFailed {
+					line.spinner.Error()
+				} else {
+					line.spinner.Complete()
+				}
+			}
+			sm.Stop()
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "PROJECT\tRESULT\tDETAILS")
+			for i, task := range tasks {
+				if results[i] == nil {
+					continue
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", projectName(task.project), results[i].result, results[i].reason)
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// cloneProject clones a single project unless it already exists on disk
+func cloneProject(ctx context.Context, project *repository.Project, progress func(backend.Progress)) (*cloneResult, error) {
+	err := project.Decode()
+	if err != nil {
+		return &cloneResult{cloneFailed, "invalid url"}, fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
+	}
+	projectPath := project.GetPath()
+
+	ok, err := isExist(projectPath)
+	if err != nil {
+		return &cloneResult{cloneFailed, "path check failed"}, fmt.Errorf("error checking path for repository %s: %w", project.Url, err)
+	}
+	if ok {
+		return &cloneResult{cloneSkipped, "already cloned"}, nil
+	}
+
+	clone := project.GetCloneOptions()
+	if err := clone.Check(); err != nil {
+		return &cloneResult{cloneFailed, "invalid clone options"}, fmt.Errorf("problem with clone options of project %s: %w", project.Url, err)
+	}
+
+	reference := cacheMirror(ctx, project.Url)
+	err = Backend.Clone(&backend.Options{
+		Context:      ctx,
+		Url:          project.Url,
+		Dir:          projectPath,
+		Branch:       clone.GetBranch(),
+		Depth:        clone.GetDepth(),
+		Filter:       clone.GetFilter(),
+		Sparse:       clone.Sparse,
+		SingleBranch: clone.GetSingleBranch(),
+		Reference:    reference,
+		Dissociate:   Dissociate,
+		Progress:     progress,
+	})
+	if err != nil {
+		return &cloneResult{cloneFailed, cloneFailure(err)}, fmt.Errorf("failed to clone repository %s: %w", project.Url, err)
+	}
+
+	return &cloneResult{cloneCloned, cloneDetails(clone, reference)}, nil
+}
+
+// cloneFailure returns the reason of a failed clone for the summary table
+func cloneFailure(err error) string {
+	var gitErr *backend.GitError
+	switch {
+	case errors.As(err, &gitErr):
+		return gitErr.Kind.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out"
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	default:
+		return "git clone failed"
+	}
+}
+
+// cloneDetails describes the clone options applied to a clone
+func cloneDetails(clone repository.CloneOptions, reference string) string {
+	var details []string
+	if branch := clone.GetBranch(); branch != "" {
+		details = append(details, "branch "+branch)
+	}
+	if depth := clone.GetDepth(); depth > 0 {
+		details = append(details, fmt.Sprintf("depth %d", depth))
+	}
+	if filter := clone.GetFilter(); filter != "" {
+		details = append(details, "filter "+filter)
+	}
+	if len(clone.Sparse) > 0 {
+		details = append(details, "sparse")
+	}
+	if reference != "" {
+		details = append(details, "cache")
+	}
+	return strings.Join(details, ", ")
+}
+
+// projectName is the name of a project in summary tables, the url when it can't be decoded
+func projectName(project *repository.Project) string {
+	if project.FQDN == "" {
+		return project.Url
+	}
+	return filepath.Join(project.FQDN, project.Folders)
+}
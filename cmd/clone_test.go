@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCloneOptions(t *testing.T) {
+	const repositories = `version: 2
+groups:
+  - name: team
+    clone:
+      depth: 1
+      filter: blob:none
+    projects:
+      - url: git@example.com:team/api.git
+      - url: git@example.com:team/web.git
+        clone:
+          depth: 0
+          sparse: [src]
+`
+	fake, root := fakeRepositories(t, repositories, nil)
+	if err := start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Projects are cloned one by one, the spinners of the command aren't of interest
+	for _, task := range collectProjects(allGroups) {
+		result, err := cloneProject(context.Background(), task.project, nil)
+		if err != nil || result.result != cloneCloned {
+			t.Fatalf("cloneProject(%s) = %+v, %v", task.project.Url, result, err)
+		}
+	}
+
+	tests := []struct {
+		path       string
+		wantDepth  int
+		wantFilter string
+		wantSparse []string
+	}{
+		{"example.com/team/api", 1, "blob:none", nil},
+		{"example.com/team/web", 0, "blob:none", []string{"src"}},
+	}
+	for _, tt := range tests {
+		repo := fake.Repos[filepath.Join(root, tt.path)]
+		if repo == nil {
+			t.Errorf("%s wasn't cloned", tt.path)
+			continue
+		}
+		if repo.Depth != tt.wantDepth || repo.Filter != tt.wantFilter || !slices.Equal(repo.Sparse, tt.wantSparse) {
+			t.Errorf("%s cloned with depth %d, filter %q and sparse %q, want %d, %q and %q",
+				tt.path, repo.Depth, repo.Filter, repo.Sparse, tt.wantDepth, tt.wantFilter, tt.wantSparse)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/cache"
+	"aww/internal/config"
+	"aww/internal/repository"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Configure applies the settings of the config file and the selected profile to every value
+// which wasn't set by a flag or an environment variable. It runs before any command.
+func Configure(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	if Debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+
+	if config.FilePath == "" {
+		config.FilePath = config.DefaultFilePath()
+	} else {
+		config.FilePath = repository.ExpandHome(config.FilePath)
+	}
+	cfg, err := config.Load(config.FilePath)
+	if err != nil {
+		return ctx, err
+	}
+
+	profile := Profile
+	if profile == "" {
+		profile = cfg.DefaultProfile
+	}
+	settings, err := cfg.Resolve(profile)
+	if err != nil {
+		return ctx, err
+	}
+	if profile != "" {
+		log.Debug().Str("profile", profile).Msg("Using profile")
+	}
+
+	// unset reports whether the value should come from the config file
+	unset := func(flag string, value bool) bool {
+		return value && !cmd.IsSet(flag)
+	}
+
+	if unset("root", settings.Root != "") {
+		repository.DestRepoPath = repository.ExpandHome(settings.Root)
+	}
+	if unset("layout", settings.Layout != "") {
+		repository.Layout = settings.Layout
+	}
+	if unset("file", settings.RepositoriesFile != "") {
+		repository.RepositoryFilePath = repository.ExpandHome(settings.RepositoriesFile)
+	}
+	if unset("jobs", settings.Jobs != 0) {
+		Jobs = settings.Jobs
+	}
+	if unset("remote", settings.Remote != "") {
+		Remote = settings.Remote
+	}
+	if unset("backend", settings.Backend != "") {
+		BackendName = settings.Backend
+	}
+	if unset("output", settings.Output != "") {
+		Output = settings.Output
+	}
+	if unset("retries", settings.Retries != nil) {
+		backend.Retry.Attempts = *settings.Retries
+	}
+	if unset("cache", settings.Cache != nil) {
+		UseCache = *settings.Cache
+	}
+	if unset("dissociate", settings.Dissociate != nil) {
+		Dissociate = *settings.Dissociate
+	}
+	if unset("cache-dir", settings.CacheDir != "") {
+		cache.Path = settings.CacheDir
+	}
+	if cache.Path == "" {
+		cache.Path = cache.DefaultPath()
+	}
+	cache.Path = repository.ExpandHome(cache.Path)
+
+	timeouts := []struct {
+		flag   string
+		value  *time.Duration
+		target *time.Duration
+	}{
+		{"clone-timeout", settings.Timeouts.Clone, &backend.Timeouts.Clone},
+		{"fetch-timeout", settings.Timeouts.Fetch, &backend.Timeouts.Fetch},
+		{"pull-timeout", settings.Timeouts.Pull, &backend.Timeouts.Pull},
+		{"push-timeout", settings.Timeouts.Push, &backend.Timeouts.Push},
+		{"local-timeout", settings.Timeouts.Local, &backend.Timeouts.Local},
+	}
+	for _, timeout := range timeouts {
+		if unset(timeout.flag, timeout.value != nil) {
+			*timeout.target = *timeout.value
+		}
+	}
+
+	switch Output {
+	case config.OutputText:
+	case config.OutputJSON:
+		log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	default:
+		return ctx, fmt.Errorf("invalid output '%s' (available: %s, %s)", Output, config.OutputText, config.OutputJSON)
+	}
+
+	return ctx, nil
+}
+
+// Config creates a CLI command managing the repositories file
+func Config() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Manage the repositories file",
+		Commands: []*cli.Command{
+			{
+				Name:      "restore",
+				Usage:     "Restore the repositories file from a backup, the newest one when no backup is given",
+				ArgsUsage: "[backup]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "list",
+						Aliases: []string{"l"},
+						Usage:   "list the backups instead of restoring one",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					path, err := restoreTarget()
+					if err != nil {
+						return err
+					}
+
+					backups, err := repository.Backups(path)
+					if err != nil {
+						return err
+					}
+					if len(backups) == 0 {
+						return fmt.Errorf("no backups of %s found in %s", path, repository.BackupPath)
+					}
+
+					if cmd.Bool("list") {
+						writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+						fmt.Fprintln(writer, "BACKUP\tTAKEN")
+						for _, backup := range backups {
+							fmt.Fprintf(writer, "%s\t%s\n", backup.Name, backup.Time.Format(time.DateTime))
+						}
+						return writer.Flush()
+					}
+
+					backup := backups[0]
+					if name := cmd.Args().First(); name != "" {
+						i := slices.IndexFunc(backups, func(b repository.Backup) bool { return b.Name == name })
+						if i < 0 {
+							return fmt.Errorf("backup %s of %s not found, list them with --list", name, path)
+						}
+						backup = backups[i]
+					}
+
+					if err := repository.Restore(path, backup); err != nil {
+						return err
+					}
+					log.Info().Str("file", path).Str("backup", backup.Name).Msg("Repositories file restored ✅")
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// restoreTarget returns the repositories file restored by the restore command
+func restoreTarget() (string, error) {
+	switch len(repository.Files) {
+	case 0:
+		return repository.RepositoryFilePath, nil
+	case 1:
+		return repository.ExpandHome(repository.Files[0]), nil
+	default:
+		return "", fmt.Errorf("restore works on a single file, select it with one --file")
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"aww/internal/cache"
+	"aww/internal/config"
+	"aww/internal/repository"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+func TestConfigureMovesTheCache(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	content := "cache_dir: " + filepath.Join(dir, "cache") + "\nprofiles:\n  work:\n    root: " + filepath.Join(dir, "work") + "\n    cache_dir: " + filepath.Join(dir, "work-cache") + "\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	filePath, cachePath, root, profile, output := config.FilePath, cache.Path, repository.DestRepoPath, Profile, Output
+	t.Cleanup(func() {
+		config.FilePath, cache.Path, repository.DestRepoPath, Profile, Output = filePath, cachePath, root, profile, output
+	})
+	Output = config.OutputText
+
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{"", filepath.Join(dir, "cache")},
+		{"work", filepath.Join(dir, "work-cache")},
+	}
+
+	for _, tt := range tests {
+		config.FilePath, cache.Path, Profile = file, "", tt.profile
+
+		if _, err := Configure(context.Background(), &cli.Command{}); err != nil {
+			t.Fatalf("Configure() error = %v", err)
+		}
+		if cache.Path != tt.want {
+			t.Errorf("profile %q: cache.Path = %s, want %s", tt.profile, cache.Path, tt.want)
+		}
+	}
+	if want := filepath.Join(dir, "work"); repository.DestRepoPath != want {
+		t.Errorf("root = %s, want the root of the profile %s", repository.DestRepoPath, want)
+	}
+
+	// Without a config file the cache is in the configuration folder
+	config.FilePath, cache.Path, Profile = filepath.Join(dir, "missing.yaml"), "", ""
+	if _, err := Configure(context.Background(), &cli.Command{}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if cache.Path != cache.DefaultPath() {
+		t.Errorf("cache.Path = %s, want %s", cache.Path, cache.DefaultPath())
+	}
+}
@@ -1,6 +1,10 @@
 package cmd
 
-import "aww/internal/repository"
+import (
+	"aww/internal/repository"
+	"context"
+	"io"
+)
 
 type conditionalOption string
 
@@ -10,4 +14,5 @@ const (
 	Unpushed    conditionalOption = "unpushed"
 )
 
-type projectAction func(project *repository.Project, groupAction *repository.GroupActions) error
+// projectAction is executed for every project, anything written to out is printed once all projects are done
+type projectAction func(ctx context.Context, out io.Writer, project *repository.Project, groupAction *repository.GroupActions) error
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines printed around changes
+const diffContext = 3
+
+// diffLine is a line of a diff, prefixed with ' ', '+' or '-'
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// writeDiff prints a unified diff of two versions of a file
+func writeDiff(out io.Writer, path string, before, after []byte) {
+	lines := diffLines(string(before), string(after))
+
+	added := color.New(color.FgGreen).SprintFunc()
+	removed := color.New(color.FgRed).SprintFunc()
+	header := color.New(color.Bold).SprintFunc()
+
+	fmt.Fprintln(out, header("--- "+path))
+	fmt.Fprintln(out, header("+++ "+path))
+
+	// Unchanged lines further than diffContext from a change are skipped
+	show := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.op == ' ' {
+			continue
+		}
+		for j := max(0, i-diffContext); j <= min(len(lines)-1, i+diffContext); j++ {
+			show[j] = true
+		}
+	}
+
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(lines); {
+		if !show[i] {
+			if lines[i].op != '+' {
+				oldLine++
+			}
+			if lines[i].op != '-' {
+				newLine++
+			}
+			i++
+			continue
+		}
+
+		end := i
+		oldCount, newCount := 0, 0
+		for end < len(lines) && show[end] {
+			if lines[end].op != '+' {
+				oldCount++
+			}
+			if lines[end].op != '-' {
+				newCount++
+			}
+			end++
+		}
+
+		fmt.Fprintln(out, color.CyanString("@@ -%d,%d +%d,%d @@", oldLine, oldCount, newLine, newCount))
+		for _, line := range lines[i:end] {
+			switch line.op {
+			case '+':
+				fmt.Fprintln(out, added("+"+line.text))
+			case '-':
+				fmt.Fprintln(out, removed("-"+line.text))
+			default:
+				fmt.Fprintln(out, " "+line.text)
+			}
+		}
+
+		oldLine += oldCount
+		newLine += newCount
+		i = end
+	}
+}
+
+// diffLines compares two texts line by line
+func diffLines(before, after string) []diffLine {
+	dmp := diffmatchpatch.New()
+	a, b, lineArray := dmp.DiffLinesToChars(before, after)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lineArray)
+
+	var lines []diffLine
+	for _, diff := range diffs {
+		op := byte(' ')
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		}
+		for _, text := range strings.SplitAfter(diff.Text, "\n") {
+			if text != "" {
+				lines = append(lines, diffLine{op: op, text: strings.TrimSuffix(text, "\n")})
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"aww/exec"
+	"aww/internal/pool"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// execTemplateData holds the variables available in the arguments of the exec command
+type execTemplateData struct {
+	Group   string
+	Url     string
+	FQDN    string
+	Folders string
+	Path    string
+}
+
+// prefixWriter writes complete lines prefixed with the project to a shared output,
+// so the output of concurrently running commands doesn't interleave within a line
+type prefixWriter struct {
+	mu     *sync.Mutex // Shared by the writers of all projects, guards out and buf
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+// Write implements io.Writer, stdout and stderr of a command are written by separate goroutines
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes the last unterminated line
+func (w *prefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+// writeLine writes a prefixed line, the caller holds mu
+func (w *prefixWriter) writeLine(line []byte) error {
+	_, err := fmt.Fprintf(w.out, "%s %s", w.prefix, line)
+	return err
+}
+
+// Exec creates a CLI command running an arbitrary command in every cloned repository.
+func Exec() *cli.Command {
+	return &cli.Command{
+		Name:      "exec",
+		Usage:     "Run a command in every cloned repository, arguments support {{.Group}}, {{.Url}}, {{.FQDN}}, {{.Folders}} and {{.Path}}",
+		ArgsUsage: "-- <command> [args...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "fail-fast",
+				Usage: "Kill the running commands and don't start new ones after the first failure",
+			},
+			&cli.BoolFlag{
+				Name:  "shell",
+				Usage: "Run the first argument as an 'sh -c' script (pipes, redirects, ...), the other arguments are appended quoted",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Maximum duration of the command in a single repository (0 disables the timeout)",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			if len(args) > 0 && args[0] == "--" {
+				args = args[1:]
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("please specify a command to run, e.g. aww git exec -- git log -1")
+			}
+
+			// Parse templates upfront, so a typo doesn't fail in every repository
+			templates := make([]*template.Template, len(args))
+			for i, arg := range args {
+				tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+				if err != nil {
+					return fmt.Errorf("invalid template in argument %q: %w", arg, err)
+				}
+				templates[i] = tmpl
+			}
+
+			err := start()
+			if err != nil {
+				return err
+			}
+
+			err = overrideGroups(cmd)
+			if err != nil {
+				return err
+			}
+
+			runCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			var mu sync.Mutex
+			tasks := collectProjects(groups)
+			skipped := make([]bool, len(tasks))
+
+			errs := pool.Run(runCtx, int(Jobs), len(tasks), func(runCtx context.Context, i int) error {
+				group, project := tasks[i].group, tasks[i].project
+
+				err := project.Decode()
+				if err != nil {
+					return fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
+				}
+				projectPath := project.GetPath()
+
+				ok, err := isExist(projectPath)
+				if err != nil {
+					return fmt.Errorf("error checking path for repository %s: %w", project.Url, err)
+				}
+				if !ok {
+					log.Debug().Str("path", projectPath).Msg("Repository not cloned, skipping")
+					skipped[i] = true
+					return nil
+				}
+
+				data := execTemplateData{
+					Group:   group.Name,
+					Url:     project.Url,
+					FQDN:    project.FQDN,
+					Folders: project.Folders,
+					Path:    projectPath,
+				}
+				var argv []string
+				if cmd.Bool("shell") {
+					argv, err = renderShellScript(templates, data)
+				} else {
+					argv, err = renderExecArgs(templates, data)
+				}
+				if err != nil {
+					return fmt.Errorf("%s: %w", projectPath, err)
+				}
+
+				if timeout := cmd.Duration("timeout"); timeout > 0 {
+					var cancelTimeout context.CancelFunc
+					runCtx, cancelTimeout = context.WithTimeout(runCtx, timeout)
+					defer cancelTimeout()
+				}
+
+				writer := &prefixWriter{
+					mu:     &mu,
+					out:    os.Stdout,
+					prefix: color.New(color.FgHiCyan).Sprintf("[%s]", filepath.Join(project.FQDN, project.Folders)),
+				}
+				_, err = exec.New().Context(runCtx).Dir(projectPath).Silent().Writer(writer).Go(argv[0], argv[1:]...)
+				if flushErr := writer.Flush(); flushErr != nil && err == nil {
+					err = flushErr
+				}
+				if err != nil {
+					if cmd.Bool("fail-fast") {
+						cancel()
+					}
+					return fmt.Errorf("%s: %w", projectPath, err)
+				}
+				return nil
+			})
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			// Summary
+			var succeeded, notRun int
+			var combinedError []error
+			for i, err := range errs {
+				switch {
+				case skipped[i]:
+				case errors.Is(err, context.Canceled) && runCtx.Err() != nil:
+					// Not started or stopped because of --fail-fast
+					notRun++
+				case err != nil:
+					combinedError = append(combinedError, err)
+				default:
+					succeeded++
+				}
+			}
+
+			success := color.New(color.FgGreen).SprintFunc()
+			failure := color.New(color.FgRed).SprintFunc()
+			fmt.Printf("\nSummary: %s, %s", success(fmt.Sprintf("%d succeeded", succeeded)), failure(fmt.Sprintf("%d failed", len(combinedError))))
+			if notRun > 0 {
+				fmt.Printf(", %d cancelled (fail-fast)", notRun)
+			}
+			fmt.Println()
+
+			return errors.Join(combinedError...)
+		},
+	}
+}
+
+// renderExecArgs renders the argument templates for a single project
+func renderExecArgs(templates []*template.Template, data execTemplateData) ([]string, error) {
+	argv := make([]string, len(templates))
+
+	for i, tmpl := range templates {
+		var builder strings.Builder
+		if err := tmpl.Execute(&builder, data); err != nil {
+			return nil, fmt.Errorf("failed to render argument: %w", err)
+		}
+		argv[i] = builder.String()
+	}
+
+	return argv, nil
+}
+
+// renderShellScript renders the first argument as an 'sh -c' script, with its template values quoted,
+// and appends the other arguments quoted
+func renderShellScript(templates []*template.Template, data execTemplateData) ([]string, error) {
+	script, err := renderExecArgs(templates[:1], execTemplateData{
+		Group:   shellQuote(data.Group),
+		Url:     shellQuote(data.Url),
+		FQDN:    shellQuote(data.FQDN),
+		Folders: shellQuote(data.Folders),
+		Path:    shellQuote(data.Path),
+	})
+	if err != nil {
+		return nil, err
+	}
+	args, err := renderExecArgs(templates[1:], data)
+	if err != nil {
+		return nil, err
+	}
+
+	words := script
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	return []string{"sh", "-c", strings.Join(words, " ")}, nil
+}
+
+// shellQuote quotes s as a single word of a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
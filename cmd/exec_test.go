@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"bytes"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var mu sync.Mutex
+	var out bytes.Buffer
+	writer := &prefixWriter{mu: &mu, out: &out, prefix: "[repo]"}
+
+	// Stdout and stderr of the command, written concurrently in small chunks
+	var wg sync.WaitGroup
+	for _, stream := range []string{"out", "err"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				for _, chunk := range []string{stream, fmt.Sprintf(" %d", i), "\n"} {
+					writer.Write([]byte(chunk))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	writer.Write([]byte("last"))
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 201 {
+		t.Fatalf("got %d lines, want 201", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[repo] ") {
+			t.Errorf("line %q isn't prefixed", line)
+		}
+	}
+	if last := lines[len(lines)-1]; last != "[repo] last" {
+		t.Errorf("last line = %q, want the flushed line", last)
+	}
+}
+
+func TestRenderShellScript(t *testing.T) {
+	data := execTemplateData{Group: "team", Path: "/work/it's a repo"}
+
+	tests := []struct {
+		name string
+		args []string
+		want string // Output of the script
+	}{
+		{"quoted template value", []string{"cd {{.Path}} 2>/dev/null; printf '%s|' {{.Path}} {{.Group}}"}, "/work/it's a repo|team|"},
+		{"quoted arguments", []string{"printf '%s|'", "a b", "$HOME", "{{.Path}}", "x;y"}, "a b|$HOME|/work/it's a repo|x;y|"},
+		{"pipe", []string{"echo {{.Group}} | tr a-z A-Z"}, "TEAM\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates := make([]*template.Template, len(tt.args))
+			for i, arg := range tt.args {
+				templates[i] = template.Must(template.New("arg").Parse(arg))
+			}
+
+			argv, err := renderShellScript(templates, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if argv[0] != "sh" || argv[1] != "-c" || len(argv) != 3 {
+				t.Fatalf("argv = %q, want a single sh -c script", argv)
+			}
+			output, err := osexec.Command(argv[0], argv[1:]...).Output()
+			if err != nil {
+				t.Fatalf("%s: %v", argv[2], err)
+			}
+			if got := string(output); got != tt.want {
+				t.Errorf("script %s printed %q, want %q", argv[2], got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecKeepsTheEnvironment(t *testing.T) {
+	_, root := fakeRepositories(t, gitRepositories, map[string]*backend.FakeRepository{
+		"example.com/tools/cli": {Branch: "main"},
+	})
+	t.Setenv("GIT_TERMINAL_PROMPT", "")
+	os.Unsetenv("GIT_TERMINAL_PROMPT")
+
+	// Prompts of the git commands of the user are left alone
+	output, err := runCommand(t, Git(), "git", "exec", "--repo", "tools", "--shell", "--", "echo prompt=${GIT_TERMINAL_PROMPT-unset}")
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if !strings.Contains(output, filepath.Join("example.com", "tools", "cli")+"] prompt=unset") {
+		t.Errorf("exec output = %q, want GIT_TERMINAL_PROMPT unset in %s", output, root)
+	}
+}
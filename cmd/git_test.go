@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const gitRepositories = `version: 2
+groups:
+  - name: team
+    projects:
+      - url: git@example.com:team/api.git
+      - url: git@example.com:team/web.git
+  - name: tools
+    projects:
+      - url: git@example.com:tools/cli.git
+`
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string // Printed projects below the root
+	}{
+		{"uncommitted", []string{"--uncommitted"}, []string{"example.com/team/api"}},
+		{"unpushed", []string{"--unpushed"}, []string{"example.com/tools/cli"}},
+		{"empty", []string{"--empty"}, []string{"example.com/team/web"}},
+		{"selected group", []string{"--uncommitted", "--repo", "tools"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, root := fakeRepositories(t, gitRepositories, map[string]*backend.FakeRepository{
+				"example.com/team/api":  {Branch: "main", Changes: []string{"main.go"}},
+				"example.com/team/web":  {Branch: "main"},
+				"example.com/tools/cli": {Branch: "main", Unpushed: []string{"fix"}},
+			})
+			// An empty repository only has its .git folder
+			if err := os.Mkdir(filepath.Join(root, "example.com/team/web/.git"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "example.com/tools/cli/main.go"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			output, err := runCommand(t, Git(), append([]string{"git", "find"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("find: %v", err)
+			}
+
+			var want []string
+			for _, path := range tt.want {
+				want = append(want, filepath.Join(root, path))
+			}
+			if got := strings.Fields(output); !slices.Equal(got, want) {
+				t.Errorf("find printed %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFindUnpushedWithoutUpstream(t *testing.T) {
+	_, root := fakeRepositories(t, gitRepositories, map[string]*backend.FakeRepository{
+		"example.com/team/api":  {Branch: "main", Unpushed: []string{"local work"}, NoUpstream: true},
+		"example.com/team/web":  {Branch: "main"},
+		"example.com/tools/cli": {Branch: "main"},
+	})
+
+	output, err := runCommand(t, Git(), "git", "find", "--unpushed")
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if want := filepath.Join(root, "example.com/team/api"); strings.TrimSpace(output) != want {
+		t.Errorf("find printed %q, want the branch without upstream %q", output, want)
+	}
+}
+
+func TestFindWithoutCondition(t *testing.T) {
+	fakeRepositories(t, gitRepositories, nil)
+
+	if _, err := runCommand(t, Git(), "git", "find"); err == nil {
+		t.Fatal("find without a condition succeeded")
+	}
+}
+
+func TestSwitchBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]string // Checked out branch of every project
+		wantErr bool
+	}{
+		{
+			name: "default branch",
+			args: nil,
+			want: map[string]string{"example.com/team/api": "main", "example.com/team/web": "develop", "example.com/tools/cli": "main"},
+		},
+		{
+			name: "named branch",
+			args: []string{"--branch", "release"},
+			want: map[string]string{"example.com/team/api": "release", "example.com/team/web": "release", "example.com/tools/cli": "release"},
+		},
+		{
+			name: "selected group",
+			args: []string{"--branch", "release", "--repo", "tools"},
+			want: map[string]string{"example.com/team/api": "feature", "example.com/team/web": "feature", "example.com/tools/cli": "release"},
+		},
+		{
+			name:    "missing branch",
+			args:    []string{"--branch", "unknown", "--repo", "tools"},
+			want:    map[string]string{"example.com/team/api": "feature", "example.com/team/web": "feature", "example.com/tools/cli": "feature"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := map[string]*backend.FakeRepository{
+				"example.com/team/api":  {Branch: "feature", DefaultBranch: "main", Branches: []string{"main", "feature", "release"}},
+				"example.com/team/web":  {Branch: "feature", DefaultBranch: "develop", Branches: []string{"develop", "feature", "release"}},
+				"example.com/tools/cli": {Branch: "feature", DefaultBranch: "main", Branches: []string{"main", "feature", "release"}},
+			}
+			fakeRepositories(t, gitRepositories, repos)
+
+			_, err := runCommand(t, Git(), append([]string{"git", "switch-branch"}, tt.args...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("switch-branch error = %v, want error %v", err, tt.wantErr)
+			}
+
+			for path, branch := range tt.want {
+				if got := repos[path].Branch; got != branch {
+					t.Errorf("%s is on %s, want %s", path, got, branch)
+				}
+			}
+		})
+	}
+}
@@ -2,27 +2,73 @@ package cmd
 
 import (
 	"aww/internal/backend"
+	"aww/internal/pool"
 	"aww/internal/repository"
+	"aww/internal/selector"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v3"
 )
 
 var (
-	Debug     bool
-	groups    []*repository.Group
+	Debug       bool
+	Jobs        int64
+	BackendName string
+	Profile     string // Profile of the config file
+	Remote      string // Remote used by fetch, pull and push, the default remote when empty
+	Output      string // Log output format
+	UseCache    bool   // Clone through the shared object cache
+	Dissociate  bool   // Copy the objects borrowed from the cache, so clones don't depend on it
+	// Backend performs git operations, replaceable (e.g. with backend.Fake) before running the commands
+	Backend   backend.Backend     = backend.NewCLI()
+	allGroups []*repository.Group // Every group of the repositories file
+	groups    []*repository.Group // Groups selected for the command
 	groupsMap map[string]int
 )
 
-// Utility function to process group projects
-func processProjects(projects []*repository.Project, groupActions *repository.GroupActions, action projectAction) error {
-	var combinedError []error
+// errNoUpstream is returned by ifUnpushed for a branch without upstream, its commits can't be compared
+var errNoUpstream = errors.New("no upstream configured")
+
+// projectTask binds a project to the group it belongs to
+type projectTask struct {
+	group   *repository.Group
+	project *repository.Project
+}
+
+// collectProjects flattens groups into a single list of projects, keeping the file order
+func collectProjects(groups []*repository.Group) []projectTask {
+	var tasks []projectTask
+
+	for _, group := range groups {
+		if len(group.Projects) == 0 {
+			log.Warn().Str("group", group.Name).Msg("Doesn't contain any projects")
+			continue
+		}
+		for _, project := range group.Projects {
+			tasks = append(tasks, projectTask{group: group, project: project})
+		}
+	}
+
+	return tasks
+}
+
+// Utility function to process group projects.
+// Projects are processed concurrently (bounded by --jobs), output of every project
+// is buffered and printed in the order of the repositories file.
+func processProjects(ctx context.Context, groups []*repository.Group, action projectAction) error {
+	tasks := collectProjects(groups)
+	outputs := make([]bytes.Buffer, len(tasks))
+
+	errs := pool.Run(ctx, int(Jobs), len(tasks), func(ctx context.Context, i int) error {
+		project := tasks[i].project
 
-	for _, project := range projects {
 		err := project.Decode()
 		if err != nil {
 			return fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
@@ -32,62 +78,171 @@ func processProjects(projects []*repository.Project, groupActions *repository.Gr
 		// Check if the project path exists
 		ok, err := isExist(projectPath)
 		if err != nil {
-			combinedError = append(combinedError, fmt.Errorf("error checking path for repository %s: %w", project.Url, err))
-			continue
+			return fmt.Errorf("error checking path for repository %s: %w", project.Url, err)
 		}
 		if !ok {
 			log.Warn().Str("path", projectPath).Msg("Repository not found")
-			continue
+			return nil
 		}
 
 		// Execute the custom handler
-		err = action(project, groupActions)
-		if err != nil {
-			combinedError = append(combinedError, err)
-		}
+		return action(ctx, &outputs[i], project, tasks[i].group.Actions)
+	})
+
+	for i := range outputs {
+		os.Stdout.Write(outputs[i].Bytes())
 	}
 
-	if len(combinedError) > 0 {
-		return errors.Join(combinedError...)
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 func start() error {
+	_, err := loadRepositories(false)
+	return err
+}
+
+// startForUpdate is start for commands saving the repositories. The repositories files stay locked
+// until the returned function is called, so other aww processes can't change them before the save.
+func startForUpdate() (func(), error) {
+	return loadRepositories(true)
+}
+
+// loadRepositories selects the backend and loads the repositories, locked for an update
+func loadRepositories(locked bool) (func(), error) {
 	var err error
 
-	if Debug {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	if BackendName != "" {
+		Backend, err = backend.New(BackendName)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	groups, err = repository.Load()
+	unlock := func() {}
+	if locked {
+		allGroups, unlock, err = repository.LoadLocked()
+	} else {
+		allGroups, err = repository.Load()
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
+	groups = allGroups
 
-	return nil
+	return unlock, nil
+}
+
+// selectionFlags are the flags selecting groups and projects, shared by all commands
+func selectionFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "repo",
+			Aliases: []string{"r"},
+			Usage:   "Operate only on the specified group names (repeatable or comma separated)",
+		},
+		&cli.StringFlag{
+			Name:    "select",
+			Aliases: []string{"s"},
+			Usage:   `Operate only on projects matching the expression, e.g. 'group=backend && url~"payments" && !label:archived'`,
+		},
+		&cli.StringSliceFlag{
+			Name:    "exclude",
+			Aliases: []string{"x"},
+			Usage:   "Skip projects whose url, path (<host>/<folders>) or group matches the glob pattern (repeatable)",
+		},
+	}
 }
 
+// overrideGroups narrows groups down to the groups and projects selected with --repo, --select and --exclude.
+// Selected groups share projects and actions with allGroups, so changes can be saved with allGroups.
 func overrideGroups(cmd *cli.Command) error {
-	groupsMap = make(map[string]int, len(groups))
+	groupsMap = make(map[string]int, len(allGroups))
 
-	for i, group := range groups {
+	for i, group := range allGroups {
 		groupsMap[group.Name] = i
 	}
 
-	if cmd.String("repo") != "" {
-		groupIndex, exists := groupsMap[cmd.String("repo")]
-		if !exists {
-			return fmt.Errorf("group '%s' not found", cmd.String("repo"))
+	names := map[string]bool{}
+	for _, name := range cmd.StringSlice("repo") {
+		if _, exists := groupsMap[name]; !exists {
+			return fmt.Errorf("group '%s' not found", name)
 		}
-		group := groups[groupIndex]
-		groups = []*repository.Group{group}
+		names[name] = true
+	}
+
+	query, err := selector.Parse(cmd.String("select"))
+	if err != nil {
+		return err
+	}
+	exclude, err := selector.Exclude(cmd.StringSlice("exclude"))
+	if err != nil {
+		return err
+	}
+	sel := selector.And(query, exclude)
+	_, selectAll := sel.(selector.All)
+
+	groups = nil
+	for _, group := range allGroups {
+		if len(names) > 0 && !names[group.Name] {
+			continue
+		}
+		if selectAll {
+			groups = append(groups, group)
+			continue
+		}
+
+		var projects []*repository.Project
+		for _, project := range group.Projects {
+			if sel.Match(selectionTarget(group, project)) {
+				projects = append(projects, project)
+			}
+		}
+		if len(projects) == 0 {
+			continue
+		}
+
+		selected := *group
+		selected.Projects = projects
+		groups = append(groups, &selected)
+	}
+
+	if len(groups) == 0 {
+		log.Warn().Msg("No projects match the selection")
 	}
 
 	return nil
 }
 
+// selectionTarget describes a project for selection expressions
+func selectionTarget(group *repository.Group, project *repository.Project) *selector.Target {
+	target := &selector.Target{
+		Group:  group.Name,
+		Url:    project.Url,
+		Labels: project.GetLabels(group),
+	}
+
+	// Invalid urls are reported by the commands, they can still be selected by url or group
+	if err := project.Decode(); err == nil {
+		target.FQDN = project.FQDN
+		target.Folders = project.Folders
+		target.Path = filepath.Join(project.FQDN, project.Folders)
+	}
+
+	return target
+}
+
+// formatLabels formats labels for list outputs, empty string when there are none
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(labels, ", ") + "]"
+}
+
 func isExist(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err != nil {
@@ -100,32 +255,42 @@ func isExist(path string) (bool, error) {
 	return true, nil
 }
 
-func ifUnpushed(projectPath string) (bool, error) {
-	unpushed, err := backend.Git.Cherry(&backend.Options{
-		Dir:            projectPath,
-		AdditionalArgs: []string{"-v"},
-	})
+// defaultBranch returns the branch origin/HEAD points to
+func defaultBranch(ctx context.Context, projectPath string) (string, error) {
+	info, err := Backend.SymbolicRef(&backend.Options{Context: ctx, Dir: projectPath})
+	if err != nil {
+		return "", err
+	}
+
+	branch, ok := strings.CutPrefix(strings.TrimSpace(info), "refs/remotes/origin/")
+	if !ok || branch == "" {
+		return "", fmt.Errorf("unexpected symbolic ref format: %s", info)
+	}
+	return branch, nil
+}
+
+// ifUnpushed reports whether the current branch has commits missing on its upstream, errNoUpstream
+// when the branch (or detached HEAD) has no upstream to compare with
+func ifUnpushed(ctx context.Context, projectPath string) (bool, error) {
+	state, err := repoState(ctx, projectPath)
 	if err != nil {
 		return false, err
 	}
-	if unpushed != "" {
-		return true, nil
+	if state.Empty() {
+		return false, nil
+	}
+	if state.Upstream == "" {
+		return false, errNoUpstream
 	}
 
-	return false, nil
+	return state.Unpushed(), nil
 }
 
-func ifUncomitted(projectPath string) (bool, error) {
-	status, err := backend.Git.Status(&backend.Options{
-		Dir:            projectPath,
-		AdditionalArgs: []string{"-s"},
-	})
+func ifUncomitted(ctx context.Context, projectPath string) (bool, error) {
+	state, err := repoState(ctx, projectPath)
 	if err != nil {
 		return false, err
 	}
-	if status != "" {
-		return true, nil
-	}
 
-	return false, nil
+	return state.Dirty(), nil
 }
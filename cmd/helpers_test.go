@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/repository"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+// fakeRepositories writes the repositories file, clones its projects below a temporary root with a Fake
+// backend and restores the globals after the test. repos are keyed by the path of the project below the root.
+func fakeRepositories(t *testing.T, content string, repos map[string]*backend.FakeRepository) (*backend.Fake, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "aww")
+	file := filepath.Join(dir, "repositories.yaml")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, destRepoPath, backupPath := repository.Files, repository.DestRepoPath, repository.BackupPath
+	savedBackend, backendName, jobs := Backend, BackendName, Jobs
+	t.Cleanup(func() {
+		repository.Files, repository.DestRepoPath, repository.BackupPath = files, destRepoPath, backupPath
+		Backend, BackendName, Jobs = savedBackend, backendName, jobs
+		states = &stateCache{entries: map[string]*stateEntry{}}
+	})
+	repository.Files = []string{file}
+	repository.DestRepoPath = root
+	repository.BackupPath = filepath.Join(dir, "backups")
+	BackendName, Jobs = "", 2
+	states = &stateCache{entries: map[string]*stateEntry{}}
+
+	byDir := make(map[string]*backend.FakeRepository, len(repos))
+	for path, repo := range repos {
+		projectPath := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(projectPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		byDir[projectPath] = repo
+	}
+	Backend = backend.NewFake(byDir)
+	return Backend.(*backend.Fake), root
+}
+
+// runCommand runs the command with the arguments and returns what it printed on stdout
+func runCommand(t *testing.T, command *cli.Command, args ...string) (string, error) {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan []byte)
+	go func() {
+		var buffer bytes.Buffer
+		io.Copy(&buffer, reader)
+		output <- buffer.Bytes()
+	}()
+
+	app := &cli.Command{Name: "aww", Commands: []*cli.Command{command}}
+	err = app.Run(context.Background(), append([]string{"aww"}, args...))
+
+	writer.Close()
+	return string(<-output), err
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"aww/internal/repository"
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Import creates a CLI command merging generated repositories files into the repositories file
+func Import() *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Merge generated repositories files into the repositories file",
+		Commands: []*cli.Command{
+			{
+				Name:      "brr",
+				Usage:     "Merge a file regenerated by brr, actions, labels and other changes made by hand are kept",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "prune",
+						Usage: "remove projects which disappeared from the generated file",
+					},
+					&cli.StringFlag{
+						Name:  "mark",
+						Usage: "label added to projects which disappeared from the generated file",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "only print the changes",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "write the changes without asking",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() != 1 {
+						return fmt.Errorf("the generated file is required")
+					}
+					generated := repository.ExpandHome(cmd.Args().First())
+
+					content, err := os.ReadFile(generated)
+					if err != nil {
+						return fmt.Errorf("error reading generated file: %w", err)
+					}
+					theirs, err := repository.Read(generated)
+					if err != nil {
+						return err
+					}
+
+					unlock, err := startForUpdate()
+					if err != nil {
+						return err
+					}
+					defer unlock()
+
+					target := repository.MainFile()
+					base, err := repository.ImportBase(target, "brr")
+					if err != nil {
+						return err
+					}
+					if base == nil {
+						log.Info().Str("file", target).Msg("First import, projects missing in the generated file are kept")
+					}
+
+					merged, report := repository.Merge(allGroups, base, theirs, repository.MergeOptions{
+						Prune: cmd.Bool("prune"),
+						Mark:  cmd.String("mark"),
+					})
+					logMergeReport(report, cmd.String("mark"))
+
+					changes, err := repository.Changes(merged)
+					if err != nil {
+						return err
+					}
+					for _, change := range changes {
+						writeDiff(os.Stdout, change.Path, change.Before, change.After)
+					}
+
+					if cmd.Bool("dry-run") {
+						return nil
+					}
+					if len(changes) > 0 {
+						if !cmd.Bool("yes") && !confirm("Write the changes?") {
+							log.Info().Msg("Nothing written")
+							return nil
+						}
+						if err := repository.Save(merged); err != nil {
+							return err
+						}
+					}
+
+					// The generated file is the base of the next import
+					if err := repository.SaveImportBase(target, "brr", content); err != nil {
+						return err
+					}
+					log.Info().Int("added", len(report.Added)).Int("disappeared", len(report.Disappeared)).Msg("Import completed ✅")
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// logMergeReport logs the projects changed and reported by a merge
+func logMergeReport(report *repository.MergeReport, mark string) {
+	for _, merged := range report.Added {
+		log.Info().Str("group", merged.Group).Str("url", merged.Project.Url).Msg("Project added")
+	}
+	for _, merged := range report.Updated {
+		log.Info().Str("group", merged.Group).Str("url", merged.Project.Url).Strs("labels", merged.Project.Labels).Msg("Labels updated")
+	}
+	for _, merged := range report.Skipped {
+		log.Info().Str("group", merged.Group).Str("url", merged.Project.Url).Msg("Project removed by hand, not added again")
+	}
+	for _, merged := range report.Disappeared {
+		event := log.Warn().Str("group", merged.Group).Str("url", merged.Project.Url)
+		switch {
+		case report.Pruned:
+			event.Msg("Project disappeared from the generated file, removed")
+		case mark != "":
+			event.Str("label", mark).Msg("Project disappeared from the generated file, marked")
+		default:
+			event.Msg("Project disappeared from the generated file, use --prune or --mark")
+		}
+	}
+	for _, merged := range report.Missing {
+		log.Debug().Str("group", merged.Group).Str("url", merged.Project.Url).Msg("Project isn't generated, kept")
+	}
+}
+
+// confirm asks a yes/no question on the terminal, anything but yes is no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"aww/internal/repository"
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Migrate creates a CLI command upgrading the repositories files to the current format
+func Migrate() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Upgrade the repositories file and the files it includes to the current format, previous versions are backed up",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, unlock, err := repository.LoadLocked()
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			migrated, err := repository.Migrate()
+			for _, path := range migrated {
+				log.Info().Str("file", path).Int("version", repository.CurrentVersion).Msg("File migrated")
+			}
+			if err != nil {
+				return err
+			}
+
+			if len(migrated) == 0 {
+				log.Info().Int("version", repository.CurrentVersion).Msg("Repositories files are up to date ✅")
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"aww/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Results of relocating a single repository
+const (
+	relocateMoved     = "moved"
+	relocateInPlace   = "in place"
+	relocateSkipped   = "skipped"
+	relocateFailed    = "failed"
+	relocateWouldMove = "would move"
+)
+
+// Relocate creates a CLI command moving cloned repositories from a previous root or layout
+// to the paths of the current configuration.
+func Relocate() *cli.Command {
+	return &cli.Command{
+		Name:  "relocate",
+		Usage: "Move cloned repositories from a previous root or layout to their current paths",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "from-root",
+				Usage: "previous root directory, the current root when empty",
+			},
+			&cli.StringFlag{
+				Name:  "from-layout",
+				Usage: "previous layout template",
+				Value: repository.DefaultLayout,
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "only print the moves",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			err := start()
+			if err != nil {
+				return err
+			}
+
+			err = overrideGroups(cmd)
+			if err != nil {
+				return err
+			}
+
+			fromLayout := cmd.String("from-layout")
+			if _, err := repository.ParseLayout(fromLayout); err != nil {
+				return err
+			}
+			fromRoot := cmd.String("from-root")
+			dryRun := cmd.Bool("dry-run")
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "FROM\tTO\tRESULT")
+
+			var errs []error
+			moved := 0
+			// Moves run sequentially, two projects may share parent folders
+			for _, task := range collectProjects(groups) {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				project := task.project
+				if err := project.Decode(); err != nil {
+					errs = append(errs, fmt.Errorf("problem with decoding project %s: %v", project.Url, err))
+					continue
+				}
+
+				root := fromRoot
+				if root == "" {
+					root = project.GetRoot()
+				}
+				oldPath, err := project.LayoutPath(root, fromLayout)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to resolve previous path of %s: %w", project.Url, err))
+					continue
+				}
+				newPath, err := project.ResolvePath()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to resolve path of %s: %w", project.Url, err))
+					continue
+				}
+
+				result, err := relocateProject(oldPath, newPath, root, dryRun)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to move %s: %w", project.Url, err))
+				}
+				if result == relocateMoved {
+					moved++
+				}
+				if result != "" {
+					fmt.Fprintf(writer, "%s\t%s\t%s\n", oldPath, newPath, result)
+				}
+			}
+
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if !dryRun {
+				log.Info().Int("moved", moved).Msg("Relocation finished")
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// relocateProject moves a repository from oldPath to newPath. Repositories are never moved
+// over an existing path, and projects which were never cloned produce no result.
+func relocateProject(oldPath, newPath, oldRoot string, dryRun bool) (string, error) {
+	oldExists, err := isExist(oldPath)
+	if err != nil {
+		return relocateFailed, err
+	}
+	newExists, err := isExist(newPath)
+	if err != nil {
+		return relocateFailed, err
+	}
+
+	switch {
+	case filepath.Clean(oldPath) == filepath.Clean(newPath) && oldExists:
+		return relocateInPlace, nil
+	case filepath.Clean(oldPath) == filepath.Clean(newPath):
+		return "", nil
+	case !oldExists && newExists:
+		return relocateInPlace, nil
+	case !oldExists:
+		return "", nil
+	case newExists:
+		return relocateSkipped, fmt.Errorf("target %s already exists", newPath)
+	case dryRun:
+		return relocateWouldMove, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return relocateFailed, err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return relocateFailed, err
+	}
+	removeEmptyParents(filepath.Dir(oldPath), oldRoot)
+
+	return relocateMoved, nil
+}
+
+// removeEmptyParents removes the now empty folders left behind by a move, stopping at the first
+// folder which still has content or at the root
+func removeEmptyParents(dir, root string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root; {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		log.Debug().Str("path", dir).Msg("Removed empty folder")
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+		dir = parent
+	}
+}
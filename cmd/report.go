@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Report logs every error aggregated in err on its own line, together with
+// the remediation hint of classified git errors. It returns the number of errors.
+func Report(err error) int {
+	errs := flattenErrors(err)
+
+	for _, e := range errs {
+		event := log.Error().Err(e)
+		if hint := backend.Hint(e); hint != "" {
+			event = event.Str("hint", hint)
+		}
+		event.Msg("Operation failed")
+	}
+
+	return len(errs)
+}
+
+// flattenErrors unpacks errors created by errors.Join into a flat list
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, flattenErrors(e)...)
+	}
+	return errs
+}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Repos creates a CLI command editing the groups and projects of the repositories file
+func Repos() *cli.Command {
+	return &cli.Command{
+		Name:  "repos",
+		Usage: "Edit groups and projects of the repositories file",
+		Commands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Add repositories to a group, the group is created when it doesn't exist",
+				ArgsUsage: "<group> <url>...",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "label",
+						Usage: "label of the added projects (repeatable)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() < 2 {
+						return fmt.Errorf("group and at least one url are required")
+					}
+					name, urls := cmd.Args().First(), cmd.Args().Tail()
+
+					unlock, err := startForUpdate()
+					if err != nil {
+						return err
+					}
+					defer unlock()
+
+					group := repository.FindGroup(allGroups, name)
+					if group == nil {
+						group = &repository.Group{Name: name}
+						allGroups = append(allGroups, group)
+						log.Info().Str("group", name).Msg("Group created")
+					}
+
+					for _, url := range urls {
+						project := &repository.Project{Url: url, Labels: cmd.StringSlice("label")}
+						if err := project.Decode(); err != nil {
+							return fmt.Errorf("invalid url %s: %w", url, err)
+						}
+						if other, existing := repository.FindProject(allGroups, url); existing != nil {
+							return fmt.Errorf("project %s is already in group '%s' as %s", url, other.Name, existing.Url)
+						}
+
+						group.AddProject(project)
+						log.Info().Str("group", name).Str("url", url).Str("path", project.GetPath()).Msg("Project added")
+					}
+
+					return repository.Save(allGroups)
+				},
+			},
+			{
+				Name:      "remove",
+				Usage:     "Remove repositories from the repositories file",
+				ArgsUsage: "<url>...",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "delete",
+						Usage: "delete the clones of the removed projects",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "delete clones with uncommitted changes, stashes or unpushed branches",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() == 0 {
+						return fmt.Errorf("at least one url is required")
+					}
+
+					unlock, err := startForUpdate()
+					if err != nil {
+						return err
+					}
+					defer unlock()
+
+					var paths []string
+					for _, url := range cmd.Args().Slice() {
+						group, project := repository.FindProject(allGroups, url)
+						if project == nil {
+							return fmt.Errorf("project %s not found", url)
+						}
+						if err := project.Decode(); err != nil {
+							return fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
+						}
+
+						// The path depends on the group, it's resolved before the project leaves it
+						projectPath := project.GetPath()
+						if cmd.Bool("delete") {
+							if err := checkDeletable(ctx, projectPath, cmd.Bool("force")); err != nil {
+								return err
+							}
+						}
+
+						group.RemoveProject(project)
+						paths = append(paths, projectPath)
+						log.Info().Str("group", group.Name).Str("url", project.Url).Msg("Project removed")
+						if len(group.Projects) == 0 {
+							log.Warn().Str("group", group.Name).Msg("Group has no projects left")
+						}
+					}
+
+					if err := repository.Save(allGroups); err != nil {
+						return err
+					}
+					if !cmd.Bool("delete") {
+						return nil
+					}
+
+					for _, projectPath := range paths {
+						ok, err := isExist(projectPath)
+						if err != nil {
+							return err
+						}
+						if !ok {
+							continue
+						}
+						if err := os.RemoveAll(projectPath); err != nil {
+							return fmt.Errorf("error deleting %s: %w", projectPath, err)
+						}
+						log.Info().Str("path", projectPath).Msg("Clone deleted")
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "move",
+				Usage:     "Move a repository to another group, the group is created when it doesn't exist",
+				ArgsUsage: "<url> <group>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() != 2 {
+						return fmt.Errorf("url and group are required")
+					}
+					url, name := cmd.Args().Get(0), cmd.Args().Get(1)
+
+					unlock, err := startForUpdate()
+					if err != nil {
+						return err
+					}
+					defer unlock()
+
+					from, project := repository.FindProject(allGroups, url)
+					if project == nil {
+						return fmt.Errorf("project %s not found", url)
+					}
+					if from.Name == name {
+						return fmt.Errorf("project %s is already in group '%s'", url, name)
+					}
+					if err := project.Decode(); err != nil {
+						return fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
+					}
+
+					to := repository.FindGroup(allGroups, name)
+					if to == nil {
+						to = &repository.Group{Name: name}
+						allGroups = append(allGroups, to)
+						log.Info().Str("group", name).Msg("Group created")
+					}
+
+					oldPath := project.GetPath()
+					from.RemoveProject(project)
+					to.AddProject(project)
+					log.Info().Str("from", from.Name).Str("to", name).Str("url", project.Url).Msg("Project moved")
+
+					if newPath := project.GetPath(); newPath != oldPath {
+						log.Warn().Str("from", oldPath).Str("to", newPath).Msg("Path of the project changed, move the clone to the new path")
+					}
+
+					return repository.Save(allGroups)
+				},
+			},
+			{
+				Name:      "rename",
+				Usage:     "Rename a group",
+				ArgsUsage: "<group> <new name>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Args().Len() != 2 {
+						return fmt.Errorf("group and new name are required")
+					}
+					name, newName := cmd.Args().Get(0), cmd.Args().Get(1)
+
+					unlock, err := startForUpdate()
+					if err != nil {
+						return err
+					}
+					defer unlock()
+
+					group := repository.FindGroup(allGroups, name)
+					if group == nil {
+						return fmt.Errorf("group '%s' not found", name)
+					}
+					if repository.FindGroup(allGroups, newName) != nil {
+						return fmt.Errorf("group '%s' already exists", newName)
+					}
+
+					group.Name = newName
+					log.Info().Str("from", name).Str("to", newName).Msg("Group renamed")
+
+					return repository.Save(allGroups)
+				},
+			},
+		},
+	}
+}
+
+// checkDeletable refuses to delete a clone holding work which isn't pushed, unless forced
+func checkDeletable(ctx context.Context, projectPath string, force bool) error {
+	if force {
+		return nil
+	}
+	ok, err := isExist(projectPath)
+	if err != nil || !ok {
+		return err
+	}
+
+	uncommitted, err := ifUncomitted(ctx, projectPath)
+	if err != nil {
+		return fmt.Errorf("checking if uncommitted failed for %s: %w", projectPath, err)
+	}
+	if uncommitted {
+		return fmt.Errorf("%s has uncommitted changes, use --force to delete it anyway", projectPath)
+	}
+
+	unpushed, err := ifUnpushed(ctx, projectPath)
+	if errors.Is(err, errNoUpstream) {
+		log.Warn().Str("path", projectPath).Msg("No upstream configured")
+		return fmt.Errorf("%s has no upstream to compare with, use --force to delete it anyway", projectPath)
+	}
+	if err != nil {
+		return fmt.Errorf("checking if unpushed failed for %s: %w", projectPath, err)
+	}
+	if unpushed {
+		return fmt.Errorf("%s has unpushed commits, use --force to delete it anyway", projectPath)
+	}
+
+	state, err := repoState(ctx, projectPath)
+	if err != nil {
+		return fmt.Errorf("checking stashes failed for %s: %w", projectPath, err)
+	}
+	if state.Stashes > 0 {
+		return fmt.Errorf("%s has %d stashed changes, use --force to delete it anyway", projectPath, state.Stashes)
+	}
+
+	branches, err := Backend.LocalBranches(&backend.Options{Context: ctx, Dir: projectPath})
+	if err != nil {
+		return fmt.Errorf("listing branches failed for %s: %w", projectPath, err)
+	}
+	var unpushedBranches []string
+	for _, branch := range branches {
+		if branch.Unpushed() {
+			unpushedBranches = append(unpushedBranches, branch.Name)
+		}
+	}
+	if len(unpushedBranches) > 0 {
+		return fmt.Errorf("%s has branches which aren't pushed (%s), use --force to delete it anyway", projectPath, strings.Join(unpushedBranches, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/repository"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReposRemoveDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    *backend.FakeRepository
+		args    []string
+		wantErr string // Part of the error, empty when the clone is deleted
+	}{
+		{
+			name: "pushed",
+			repo: &backend.FakeRepository{Branch: "main", Pushed: []string{"initial"}},
+		},
+		{
+			name:    "uncommitted changes",
+			repo:    &backend.FakeRepository{Branch: "main", Pushed: []string{"initial"}, Changes: []string{"go.mod"}},
+			wantErr: "has uncommitted changes",
+		},
+		{
+			name:    "unpushed commits",
+			repo:    &backend.FakeRepository{Branch: "main", Pushed: []string{"initial"}, Unpushed: []string{"fix"}},
+			wantErr: "has unpushed commits",
+		},
+		{
+			name:    "no upstream",
+			repo:    &backend.FakeRepository{Branch: "main", Pushed: []string{"initial"}, NoUpstream: true},
+			wantErr: "has no upstream to compare with",
+		},
+		{
+			name:    "stashes",
+			repo:    &backend.FakeRepository{Branch: "main", Pushed: []string{"initial"}, Stashes: 2},
+			wantErr: "has 2 stashed changes",
+		},
+		{
+			name:    "other branches",
+			repo:    &backend.FakeRepository{Branch: "main", Pushed: []string{"initial"}, BranchesAhead: map[string]int{"feature": 1, "local": -1, "merged": 0}},
+			wantErr: "has branches which aren't pushed (feature, local)",
+		},
+		{
+			name: "forced",
+			repo: &backend.FakeRepository{Branch: "main", Pushed: []string{"initial"}, Unpushed: []string{"fix"}, Stashes: 1},
+			args: []string{"--force"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, root := fakeRepositories(t, gitRepositories, map[string]*backend.FakeRepository{"example.com/team/api": tt.repo})
+			clone := filepath.Join(root, "example.com", "team", "api")
+
+			args := append([]string{"repos", "remove", "--delete"}, tt.args...)
+			_, err := runCommand(t, Repos(), append(args, "git@example.com:team/api.git")...)
+
+			groups, loadErr := repository.Load()
+			if loadErr != nil {
+				t.Fatal(loadErr)
+			}
+			_, project := repository.FindProject(groups, "git@example.com:team/api.git")
+			_, statErr := os.Stat(clone)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("repos remove: %v", err)
+				}
+				if project != nil || statErr == nil {
+					t.Errorf("project kept in the file: %v, clone kept: %v", project != nil, statErr == nil)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("repos remove error = %v, want %q", err, tt.wantErr)
+			}
+			if project == nil || statErr != nil {
+				t.Errorf("project removed from the file: %v, clone deleted: %v", project == nil, statErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/pool"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// stateEntry is the state of a single repository, collected at most once
+type stateEntry struct {
+	once  sync.Once
+	state *backend.RepoState
+	err   error
+}
+
+// stateCache keeps the state of every repository for the duration of a command,
+// so checks like uncommitted/unpushed share a single git status per repository
+type stateCache struct {
+	mu      sync.Mutex
+	entries map[string]*stateEntry
+}
+
+var states = &stateCache{entries: map[string]*stateEntry{}}
+
+// repoState returns the state of the repository in projectPath, collecting it on first use
+func repoState(ctx context.Context, projectPath string) (*backend.RepoState, error) {
+	states.mu.Lock()
+	entry, ok := states.entries[projectPath]
+	if !ok {
+		entry = &stateEntry{}
+		states.entries[projectPath] = entry
+	}
+	states.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.state, entry.err = Backend.State(&backend.Options{
+			Context: ctx,
+			Dir:     projectPath,
+		})
+	})
+
+	return entry.state, entry.err
+}
+
+// forgetState drops the cached state of a repository after it was modified
+func forgetState(projectPath string) {
+	states.mu.Lock()
+	defer states.mu.Unlock()
+
+	delete(states.entries, projectPath)
+}
+
+// collectStates collects the state of every project concurrently. Results are aligned
+// with tasks, the state of projects which are not cloned is nil.
+func collectStates(ctx context.Context, tasks []projectTask) ([]*backend.RepoState, []error) {
+	result := make([]*backend.RepoState, len(tasks))
+
+	errs := pool.Run(ctx, int(Jobs), len(tasks), func(ctx context.Context, i int) error {
+		project := tasks[i].project
+
+		err := project.Decode()
+		if err != nil {
+			return fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
+		}
+		projectPath := project.GetPath()
+
+		ok, err := isExist(projectPath)
+		if err != nil {
+			return fmt.Errorf("error checking path for repository %s: %w", project.Url, err)
+		}
+		if !ok {
+			return nil
+		}
+
+		state, err := repoState(ctx, projectPath)
+		if err != nil {
+			return fmt.Errorf("failed to collect state of %s: %w", projectPath, err)
+		}
+		result[i] = state
+		return nil
+	})
+
+	return result, errs
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/pool"
+	"aww/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Available --sort values of the status command, "file" keeps the order of the repositories file
+var statusSortKeys = []string{"file", "path", "branch", "age", "dirty", "ahead", "behind"}
+
+// statusRow is a single project line of the status dashboard
+type statusRow struct {
+	group         string
+	project       *repository.Project
+	state         *backend.RepoState // nil when the project is not cloned
+	defaultBranch string
+	lastCommit    time.Time
+	err           error
+}
+
+func (r *statusRow) path() string {
+	return filepath.Join(r.project.FQDN, r.project.Folders)
+}
+
+func (r *statusRow) dirtyCount() int {
+	if r.state == nil {
+		return 0
+	}
+	return r.state.Staged + r.state.Unstaged + r.state.Untracked + r.state.Conflicts
+}
+
+// Status creates a CLI command printing the state of every repository.
+func Status() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "Display a dashboard of the workspace grouped by group name",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "only-dirty",
+				Usage: "Show only repositories with uncommitted changes (and those whose state couldn't be read)",
+			},
+			&cli.StringFlag{
+				Name:  "sort",
+				Usage: fmt.Sprintf("Sort projects within a group by: %s", strings.Join(statusSortKeys, ", ")),
+				Value: "file",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			err := start()
+			if err != nil {
+				return err
+			}
+
+			sortKey := cmd.String("sort")
+			if !slices.Contains(statusSortKeys, sortKey) {
+				return fmt.Errorf("unknown sort key '%s' (available: %s)", sortKey, strings.Join(statusSortKeys, ", "))
+			}
+
+			err = overrideGroups(cmd)
+			if err != nil {
+				return err
+			}
+
+			tasks := collectProjects(groups)
+			rows := make([]*statusRow, len(tasks))
+			repoStates, errs := collectStates(ctx, tasks)
+
+			// Default branch and last commit are only needed by the dashboard, collect them separately
+			pool.Run(ctx, int(Jobs), len(tasks), func(ctx context.Context, i int) error {
+				rows[i] = &statusRow{
+					group:   tasks[i].group.Name,
+					project: tasks[i].project,
+					state:   repoStates[i],
+					err:     errs[i],
+				}
+				if repoStates[i] == nil {
+					return nil
+				}
+
+				projectPath := tasks[i].project.GetPath()
+				rows[i].defaultBranch, _ = defaultBranch(ctx, projectPath)
+				rows[i].lastCommit, rows[i].err = Backend.LastCommit(&backend.Options{Context: ctx, Dir: projectPath})
+				return nil
+			})
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var combinedError []error
+			byGroup := map[string][]*statusRow{}
+			for _, row := range rows {
+				if row.err != nil {
+					combinedError = append(combinedError, row.err)
+				}
+				// Failures are always shown, the state of the repository is unknown
+				if cmd.Bool("only-dirty") && row.err == nil && row.dirtyCount() == 0 {
+					continue
+				}
+				byGroup[row.group] = append(byGroup[row.group], row)
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "GROUP\tPROJECT\tSTATUS\tBRANCH\tDEFAULT\tAHEAD/BEHIND\tSTAGED/UNSTAGED/UNTRACKED\tSTASH\tLAST COMMIT")
+			for _, group := range groups {
+				groupRows := byGroup[group.Name]
+				sortStatusRows(groupRows, sortKey)
+
+				for i, row := range groupRows {
+					name := ""
+					if i == 0 {
+						name = group.Name
+					}
+					fmt.Fprintf(writer, "%s\t%s\n", name, formatStatusRow(row))
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+
+			return errors.Join(combinedError...)
+		},
+	}
+}
+
+// formatStatusRow formats the columns of a row after the group name
+func formatStatusRow(row *statusRow) string {
+	if row.err != nil && row.state == nil {
+		return strings.Join([]string{row.path(), "error", "-", "-", "-", "-", "-", "-"}, "\t")
+	}
+	if row.state == nil {
+		return strings.Join([]string{row.path(), "missing", "-", "-", "-", "-", "-", "-"}, "\t")
+	}
+
+	state := row.state
+	branch := state.Branch
+	if state.Detached {
+		branch = "(detached)"
+	}
+
+	defaultBranch := row.defaultBranch
+	if defaultBranch == "" {
+		defaultBranch = "-"
+	} else if defaultBranch == branch {
+		defaultBranch = "="
+	}
+
+	aheadBehind := "-"
+	if state.Upstream != "" {
+		aheadBehind = fmt.Sprintf("+%d/-%d", state.Ahead, state.Behind)
+	}
+
+	dirty := fmt.Sprintf("%d/%d/%d", state.Staged, state.Unstaged, state.Untracked)
+	if state.Conflicts > 0 {
+		dirty += fmt.Sprintf(" (%d conflicts)", state.Conflicts)
+	}
+
+	return strings.Join([]string{
+		row.path(),
+		"cloned",
+		branch,
+		defaultBranch,
+		aheadBehind,
+		dirty,
+		fmt.Sprint(state.Stashes),
+		formatAge(row.lastCommit),
+	}, "\t")
+}
+
+// sortStatusRows sorts rows in place, missing repositories always go last
+func sortStatusRows(rows []*statusRow, key string) {
+	less := func(a, b *statusRow) bool {
+		switch key {
+		case "path":
+			return a.path() < b.path()
+		case "branch":
+			return a.state.Branch < b.state.Branch
+		case "age":
+			// Most recently changed first
+			return a.lastCommit.After(b.lastCommit)
+		case "dirty":
+			return a.dirtyCount() > b.dirtyCount()
+		case "ahead":
+			return a.state.Ahead > b.state.Ahead
+		case "behind":
+			return a.state.Behind > b.state.Behind
+		default:
+			return false
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].state == nil || rows[j].state == nil {
+			return rows[i].state != nil && rows[j].state == nil
+		}
+		return less(rows[i], rows[j])
+	})
+}
+
+// formatAge formats the time elapsed since t in the largest fitting unit
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	age := time.Since(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	case age < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	case age < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(age.Hours()/24/30))
+	default:
+		return fmt.Sprintf("%dy ago", int(age.Hours()/24/365))
+	}
+}
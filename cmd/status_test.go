@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStatusOnlyDirty(t *testing.T) {
+	fake, root := fakeRepositories(t, gitRepositories, map[string]*backend.FakeRepository{
+		"example.com/team/api":  {Branch: "main", Changes: []string{"main.go"}},
+		"example.com/team/web":  {Branch: "main"},
+		"example.com/tools/cli": {Branch: "main"},
+	})
+	fake.Errors["state "+filepath.Join(root, "example.com/tools/cli")] = errors.New("corrupt index")
+
+	output, err := runCommand(t, Status(), "status", "--only-dirty")
+	if err == nil || !strings.Contains(err.Error(), "corrupt index") {
+		t.Errorf("status error = %v, want the failure of cli", err)
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n")[1:] {
+		// Both rows are the first of their group: group, project, status...
+		fields := strings.Fields(line)
+		got = append(got, fields[1]+" "+fields[2])
+	}
+	if want := []string{"example.com/team/api cloned", "example.com/tools/cli error"}; !slices.Equal(got, want) {
+		t.Errorf("status rows = %q, want %q:\n%s", got, want, output)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/pool"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Results of syncing a single repository
+const (
+	syncUpdated  = "updated"
+	syncUpToDate = "up to date"
+	syncSkipped  = "skipped"
+	syncFailed   = "failed"
+)
+
+// syncResult is the outcome of syncing a single repository
+type syncResult struct {
+	result string
+	reason string
+}
+
+// Sync creates a CLI command fetching all repositories and fast-forwarding the clean ones.
+func Sync() *cli.Command {
+	return &cli.Command{
+		Name:  "sync",
+		Usage: "Fetch all repositories and fast-forward the current branch of the clean ones",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "rebase",
+				Usage: "Rebase local-only commits onto the upstream when the branch diverged",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			err := start()
+			if err != nil {
+				return err
+			}
+
+			err = overrideGroups(cmd)
+			if err != nil {
+				return err
+			}
+
+			rebase := cmd.Bool("rebase")
+			tasks := collectProjects(groups)
+			results := make([]*syncResult, len(tasks))
+
+			errs := pool.Run(ctx, int(Jobs), len(tasks), func(ctx context.Context, i int) error {
+				project := tasks[i].project
+
+				err := project.Decode()
+				if err != nil {
+					results[i] = &syncResult{syncFailed, "invalid url"}
+					return fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
+				}
+				projectPath := project.GetPath()
+
+				ok, err := isExist(projectPath)
+				if err != nil {
+					results[i] = &syncResult{syncFailed, "path check failed"}
+					return fmt.Errorf("error checking path for repository %s: %w", project.Url, err)
+				}
+				if !ok {
+					results[i] = &syncResult{syncSkipped, "not cloned"}
+					return nil
+				}
+
+				results[i], err = syncProject(ctx, projectPath, rebase)
+				return err
+			})
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "PROJECT\tRESULT\tDETAILS")
+			for i, task := range tasks {
+				if results[i] == nil {
+					continue
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", filepath.Join(task.project.FQDN, task.project.Folders), results[i].result, results[i].reason)
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// syncProject fetches a repository and updates its current branch when it's safe.
+// Repositories which can't be updated safely are reported as skipped with the reason.
+func syncProject(ctx context.Context, projectPath string, rebase bool) (*syncResult, error) {
+	options := &backend.Options{Context: ctx, Dir: projectPath, Remote: Remote}
+
+	// The branch is compared with its upstream, the remote of the upstream has to be fetched
+	state, err := repoState(ctx, projectPath)
+	if err != nil {
+		return &syncResult{syncFailed, "state unknown"}, fmt.Errorf("failed to collect state of %s: %w", projectPath, err)
+	}
+	if remote := upstreamRemote(state.Upstream); remote != "" {
+		options.Remote = remote
+	}
+
+	err = Backend.Fetch(options)
+	if err != nil {
+		return &syncResult{syncFailed, "fetch failed"}, fmt.Errorf("fetch failed for %s: %w", projectPath, err)
+	}
+
+	// The fetch moved the upstream, the state has to be collected again
+	forgetState(projectPath)
+	state, err = repoState(ctx, projectPath)
+	if err != nil {
+		return &syncResult{syncFailed, "state unknown"}, fmt.Errorf("failed to collect state of %s: %w", projectPath, err)
+	}
+
+	switch {
+	case state.Detached:
+		return &syncResult{syncSkipped, "detached HEAD"}, nil
+	case state.Upstream == "":
+		return &syncResult{syncSkipped, fmt.Sprintf("no upstream for branch %s", state.Branch)}, nil
+	case state.Modified():
+		// Untracked files don't prevent the update, git refuses to overwrite them
+		return &syncResult{syncSkipped, "uncommitted changes"}, nil
+	case state.Behind == 0 && state.Ahead > 0:
+		return &syncResult{syncUpToDate, fmt.Sprintf("%d local commits not pushed", state.Ahead)}, nil
+	case state.Behind == 0:
+		return &syncResult{syncUpToDate, ""}, nil
+	case state.Ahead > 0 && !rebase:
+		return &syncResult{syncSkipped, fmt.Sprintf("diverged from %s (%d ahead, %d behind)", state.Upstream, state.Ahead, state.Behind)}, nil
+	case state.Ahead > 0:
+		log.Debug().Str("path", projectPath).Int("ahead", state.Ahead).Int("behind", state.Behind).Msg("Rebasing local commits")
+		err = Backend.Rebase(options)
+		if err != nil {
+			return &syncResult{syncFailed, "rebase failed, aborted"}, fmt.Errorf("rebase failed for %s: %w", projectPath, err)
+		}
+		forgetState(projectPath)
+		return &syncResult{syncUpdated, fmt.Sprintf("rebased %d local commits onto %s", state.Ahead, state.Upstream)}, nil
+	default:
+		err = Backend.FastForward(options)
+		if err != nil {
+			return &syncResult{syncFailed, "fast-forward failed"}, fmt.Errorf("fast-forward failed for %s: %w", projectPath, err)
+		}
+		forgetState(projectPath)
+		return &syncResult{syncUpdated, fmt.Sprintf("fast-forwarded %d commits", state.Behind)}, nil
+	}
+}
+
+// upstreamRemote returns the remote of an upstream like "origin/main", empty for a local upstream
+func upstreamRemote(upstream string) string {
+	remote, _, ok := strings.Cut(upstream, "/")
+	if !ok {
+		return ""
+	}
+	return remote
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name        string
+		repo        *backend.FakeRepository
+		rebase      bool
+		want        string // Result and details of the project
+		wantBehind  int
+		wantFetched []string
+	}{
+		{
+			name:        "fast-forward",
+			repo:        &backend.FakeRepository{Branch: "main", Behind: 2},
+			want:        "updated fast-forwarded 2 commits",
+			wantFetched: []string{"origin"},
+		},
+		{
+			name:        "upstream of another remote",
+			repo:        &backend.FakeRepository{Branch: "main", Behind: 1, Remote: "upstream"},
+			want:        "updated fast-forwarded 1 commits",
+			wantFetched: []string{"upstream"},
+		},
+		{
+			name:        "untracked files",
+			repo:        &backend.FakeRepository{Branch: "main", Behind: 1, Untracked: []string{"notes.txt"}},
+			want:        "updated fast-forwarded 1 commits",
+			wantFetched: []string{"origin"},
+		},
+		{
+			name:        "uncommitted changes",
+			repo:        &backend.FakeRepository{Branch: "main", Behind: 1, Changes: []string{"main.go"}},
+			want:        "skipped uncommitted changes",
+			wantBehind:  1,
+			wantFetched: []string{"origin"},
+		},
+		{
+			name:        "diverged",
+			repo:        &backend.FakeRepository{Branch: "main", Behind: 1, Unpushed: []string{"fix"}},
+			want:        "skipped diverged from origin/main (1 ahead, 1 behind)",
+			wantBehind:  1,
+			wantFetched: []string{"origin"},
+		},
+		{
+			name:        "rebase",
+			repo:        &backend.FakeRepository{Branch: "main", Behind: 1, Unpushed: []string{"fix"}},
+			rebase:      true,
+			want:        "updated rebased 1 local commits onto origin/main",
+			wantFetched: []string{"origin"},
+		},
+		{
+			name:        "no upstream",
+			repo:        &backend.FakeRepository{Branch: "local", NoUpstream: true},
+			want:        "skipped no upstream for branch local",
+			wantFetched: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeRepositories(t, gitRepositories, map[string]*backend.FakeRepository{"example.com/tools/cli": tt.repo})
+
+			args := []string{"sync", "--repo", "tools"}
+			if tt.rebase {
+				args = append(args, "--rebase")
+			}
+			output, err := runCommand(t, Git(), append([]string{"git"}, args...)...)
+			if err != nil {
+				t.Fatalf("sync: %v", err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(output), "\n")
+			if got := strings.Join(strings.Fields(lines[len(lines)-1])[1:], " "); got != tt.want {
+				t.Errorf("sync reported %q, want %q", got, tt.want)
+			}
+			if tt.repo.Behind != tt.wantBehind {
+				t.Errorf("%d commits behind after the sync, want %d", tt.repo.Behind, tt.wantBehind)
+			}
+			if !slices.Equal(tt.repo.Fetched, tt.wantFetched) {
+				t.Errorf("fetched remotes %q, want %q", tt.repo.Fetched, tt.wantFetched)
+			}
+		})
+	}
+}
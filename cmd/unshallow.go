@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"aww/internal/backend"
+	"aww/internal/pool"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Results of unshallowing a single repository
+const (
+	unshallowDeepened = "deepened"
+	unshallowComplete = "complete"
+	unshallowSkipped  = "skipped"
+	unshallowFailed   = "failed"
+)
+
+// Unshallow creates a CLI command fetching the history missing in shallow clones.
+func Unshallow() *cli.Command {
+	return &cli.Command{
+		Name:  "unshallow",
+		Usage: "Fetch the history missing in shallow clones",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "depth",
+				Usage: "deepen by the given number of commits, the full history when 0",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			err := start()
+			if err != nil {
+				return err
+			}
+
+			err = overrideGroups(cmd)
+			if err != nil {
+				return err
+			}
+
+			depth := int(cmd.Int("depth"))
+			if depth < 0 {
+				return fmt.Errorf("depth must be positive, got %d", depth)
+			}
+
+			tasks := collectProjects(groups)
+			results := make([]string, len(tasks))
+
+			errs := pool.Run(ctx, int(Jobs), len(tasks), func(ctx context.Context, i int) error {
+				project := tasks[i].project
+
+				err := project.Decode()
+				if err != nil {
+					results[i] = unshallowFailed
+					return fmt.Errorf("problem with decoding project %s: %v", project.Url, err)
+				}
+				projectPath := project.GetPath()
+
+				ok, err := isExist(projectPath)
+				if err != nil {
+					results[i] = unshallowFailed
+					return fmt.Errorf("error checking path for repository %s: %w", project.Url, err)
+				}
+				if !ok {
+					results[i] = unshallowSkipped
+					return nil
+				}
+
+				deepened, err := Backend.Unshallow(&backend.Options{Context: ctx, Dir: projectPath, Remote: Remote, Depth: depth})
+				switch {
+				case err != nil:
+					results[i] = unshallowFailed
+					return fmt.Errorf("unshallow failed for %s: %w", projectPath, err)
+				case deepened:
+					results[i] = unshallowDeepened
+				default:
+					results[i] = unshallowComplete
+				}
+				return nil
+			})
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "PROJECT\tRESULT")
+			for i, task := range tasks {
+				if results[i] == "" {
+					continue
+				}
+				fmt.Fprintf(writer, "%s\t%s\n", filepath.Join(task.project.FQDN, task.project.Folders), results[i])
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"aww/internal/repository"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v3"
+)
+
+// Validate creates a CLI command reporting every problem of the repositories files
+func Validate() *cli.Command {
+	return &cli.Command{
+		Name:  "validate",
+		Usage: "Check the repositories file and the files it includes",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "schema",
+				Usage: "print the JSON Schema of repositories files instead",
+			},
+			&cli.BoolFlag{
+				Name:  "strict",
+				Usage: "fail on warnings too",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Bool("schema") {
+				_, err := os.Stdout.Write(repository.Schema)
+				return err
+			}
+
+			problems, err := repository.Validate()
+			if err != nil {
+				return err
+			}
+
+			var errorCount, warningCount int
+			for _, problem := range problems {
+				fmt.Println(problem)
+				if problem.Severity == repository.SeverityError {
+					errorCount++
+				} else {
+					warningCount++
+				}
+			}
+
+			if errorCount > 0 || (cmd.Bool("strict") && warningCount > 0) {
+				return fmt.Errorf("found %d errors and %d warnings", errorCount, warningCount)
+			}
+			log.Info().Int("warnings", warningCount).Msg("Repositories file is valid ✅")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package exec
+
+import (
+	"sync"
+)
+
+// MaxStderrSize is the number of trailing bytes of stderr kept for error reporting
+const MaxStderrSize = 64 * 1024
+
+// tailBuffer is a writer which keeps only the last max bytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+// Write implements io.Writer, it never fails
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.max:]...)
+	}
+
+	return len(p), nil
+}
+
+// String returns the kept bytes
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.buf)
+}
@@ -0,0 +1,40 @@
+package exec
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	sshCommandOnce sync.Once
+	sshCommand     string
+)
+
+// nonInteractiveEnv returns the environment of commands, git and ssh are told to fail instead of
+// prompting. Commands run outside of the foreground process group of the terminal (see
+// setProcessGroup) and would be stopped waiting for the answer to a prompt on /dev/tty:
+// credentials, key passphrases or the confirmation of unknown host keys.
+func nonInteractiveEnv() []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	// GIT_SSH_COMMAND takes precedence over GIT_SSH, a custom ssh program is left alone
+	if os.Getenv("GIT_SSH") != "" && os.Getenv("GIT_SSH_COMMAND") == "" {
+		return env
+	}
+
+	sshCommandOnce.Do(func() {
+		sshCommand = os.Getenv("GIT_SSH_COMMAND")
+		if sshCommand == "" {
+			// GIT_SSH_COMMAND overrides core.sshCommand, the configured command is kept
+			output, _ := exec.Command("git", "config", "--get", "core.sshCommand").Output()
+			sshCommand = strings.TrimSpace(string(output))
+		}
+		if sshCommand == "" {
+			sshCommand = "ssh"
+		}
+		sshCommand += " -o BatchMode=yes"
+	})
+	return append(env, "GIT_SSH_COMMAND="+sshCommand)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package exec
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on platforms without process groups, the default
+// cancel behaviour of exec.CommandContext kills only the command itself
+func setProcessGroup(cmd *exec.Cmd) {}
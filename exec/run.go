@@ -2,6 +2,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -14,15 +15,19 @@ import (
 // New returns Runner with default settings
 func New() *Runner {
 	return &Runner{
+		ctx:    context.Background(),
 		silent: false,
 		output: false,
 	}
 }
 
 type Runner struct {
-	dir    string
-	silent bool
-	output bool
+	ctx            context.Context
+	dir            string
+	silent         bool
+	output         bool
+	nonInteractive bool
+	writer         io.Writer
 }
 
 // CommandRunner is for running the command
@@ -42,41 +47,76 @@ func (r *Runner) Output() *Runner {
 	return r
 }
 
+// Writer streams both stdout and stderr of the command to w and returns the runner.
+// It takes precedence over the silent and output modes for the command streams.
+func (r *Runner) Writer(w io.Writer) *Runner {
+	r.writer = w
+	return r
+}
+
+// NonInteractive makes git and ssh fail instead of prompting (see nonInteractiveEnv) and returns the runner.
+// Meant for the git operations of aww, commands of the user keep the environment as is.
+func (r *Runner) NonInteractive() *Runner {
+	r.nonInteractive = true
+	return r
+}
+
 // Dir sets the working directory for the runner and returns the runner.
 func (r *Runner) Dir(path string) *Runner {
 	r.dir = path
 	return r
 }
 
+// Context sets the context used to cancel the command and returns the runner.
+// On cancel the whole process group of the command is killed.
+func (r *Runner) Context(ctx context.Context) *Runner {
+	if ctx != nil {
+		r.ctx = ctx
+	}
+	return r
+}
+
 // Go executes a command with behavior determined by Runner's fields.
 // - If `output` is true, captures and returns the command's stdout.
 // - If `silent` is true, suppresses logs and command output.
+// - If `writer` is set, streams stdout and stderr of the command to it.
 // - If `dir` is set, runs the command in the specified directory.
+// - If `nonInteractive` is true, git and ssh don't prompt for credentials or host keys.
+// - If `ctx` is cancelled, kills the command together with its children.
 func (r *Runner) Go(command string, args ...string) (string, error) {
-	cmd := exec.Command(command, args...)
+	cmd := exec.CommandContext(r.ctx, command, args...)
+	setProcessGroup(cmd)
 
 	// Set working directory if specified
 	if r.dir != "" {
 		cmd.Dir = r.dir
 	}
+	if r.nonInteractive {
+		cmd.Env = nonInteractiveEnv()
+	}
 
-	// Set output streams
+	// Set output streams, the tail of stderr is always captured for error reporting
 	var outputBuffer bytes.Buffer
+	errorBuffer := newTailBuffer(MaxStderrSize)
 	if r.silent {
 		cmd.Stdout = io.Discard
-		cmd.Stderr = io.Discard
+		cmd.Stderr = errorBuffer
 
 		if r.output {
 			cmd.Stdout = &outputBuffer
 		}
 	} else {
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = io.MultiWriter(os.Stderr, errorBuffer)
 		if r.output {
 			cmd.Stdout = &outputBuffer
 		} else {
 			cmd.Stdout = os.Stderr
 		}
 	}
+	if r.writer != nil {
+		cmd.Stdout = r.writer
+		cmd.Stderr = io.MultiWriter(r.writer, errorBuffer)
+	}
 
 	// Log command if not silent
 	if !r.silent {
@@ -86,7 +126,21 @@ func (r *Runner) Go(command string, args ...string) (string, error) {
 	// Run the command
 	err := CommandRunner(cmd)
 	if err != nil {
-		return outputBuffer.String(), &RunError{cmd, err}
+		// Report the cancellation rather than the signal which killed the process
+		if ctxErr := r.ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		runErr := &RunError{
+			Command:   cmd,
+			Args:      cmd.Args,
+			ExecError: err,
+			ExitCode:  -1,
+			Stderr:    errorBuffer.String(),
+		}
+		if cmd.ProcessState != nil {
+			runErr.ExitCode = cmd.ProcessState.ExitCode()
+		}
+		return outputBuffer.String(), runErr
 	}
 
 	// Return captured output if `output` is enabled
@@ -101,10 +155,45 @@ func (r *Runner) Go(command string, args ...string) (string, error) {
 // RunError represents an error that occurred while running a command.
 type RunError struct {
 	Command   *exec.Cmd
+	Args      []string // Full argv of the command
 	ExecError error
+	ExitCode  int    // Exit code of the command, -1 if it didn't exit normally (not started, killed)
+	Stderr    string // Tail of the standard error of the command, at most MaxStderrSize bytes
 }
 
 // Error implements the error interface for RunError.
 func (e *RunError) Error() string {
-	return fmt.Sprintf("%s: %s", e.Command.Path, e.ExecError)
+	msg := fmt.Sprintf("%s: %s", strings.Join(e.Args, " "), e.ExecError)
+	if reason := e.Reason(); reason != "" {
+		msg += ": " + reason
+	}
+	return msg
+}
+
+// Reason returns the most relevant lines of stderr, the "fatal:"/"error:"/"ssh:" lines
+// printed by git or the last line if there are none.
+func (e *RunError) Reason() string {
+	var reasons []string
+	var last string
+
+	for _, line := range strings.Split(e.Stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		last = line
+		if strings.HasPrefix(line, "fatal:") || strings.HasPrefix(line, "error:") || strings.HasPrefix(line, "ssh:") {
+			reasons = append(reasons, line)
+		}
+	}
+
+	if len(reasons) == 0 {
+		return last
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// Unwrap returns the underlying exec error.
+func (e *RunError) Unwrap() error {
+	return e.ExecError
 }
@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Names of the available backends
+const (
+	NameCLI   = "cli"
+	NameGoGit = "go-git"
+)
+
+type Options struct {
+	Context        context.Context // Cancels the git process, defaults to context.Background()
+	Url            string
+	Dir            string
+	Branch         string
+	CommitMsg      string         // For commit message
+	Depth          int            // Clone: history depth, 0 for the full history. Unshallow: commits to deepen by, 0 for all
+	Filter         string         // Clone: partial clone filter (e.g. blob:none)
+	Sparse         []string       // Clone: sparse checkout patterns, the full worktree when empty
+	SingleBranch   bool           // Clone: only fetch options.Branch (or the default branch)
+	Reference      string         // Clone: repository whose objects are borrowed when it exists (e.g. a cache mirror)
+	Dissociate     bool           // Clone: copy the borrowed objects, so the clone doesn't depend on options.Reference
+	Progress       func(Progress) // Clone: receives the progress reported by git while it runs
+	Remote         string         // For push/pull remote
+	AdditionalArgs []string
+}
+
+// Backend defines common git operations
+type Backend interface {
+	// Clone clones options.Url into options.Dir
+	Clone(options *Options) error
+	// Mirror creates a bare mirror of options.Url in options.Dir, or fetches the new objects of an existing one
+	Mirror(options *Options) error
+	// GC compacts the objects of the repository in options.Dir, unreachable objects are kept
+	GC(options *Options) error
+	// Unshallow fetches the missing history of a shallow clone, reporting false for complete clones
+	Unshallow(options *Options) (deepened bool, err error)
+	// Status retrieves git status
+	Status(options *Options) (output string, err error)
+	// Cherry lists commits not pushed to the upstream
+	Cherry(options *Options) (output string, err error)
+	// Push pushes the local branch to the remote
+	Push(options *Options) error
+	// Commit commits changes with the provided message
+	Commit(options *Options) error
+	// Add stages all changes
+	Add(options *Options) error
+	// Pull pulls the latest changes from the remote
+	Pull(options *Options) error
+	// Fetch fetches options.Remote (or the default remote) and prunes deleted branches
+	Fetch(options *Options) error
+	// FastForward fast-forwards the current branch to its upstream, failing if it's not possible
+	FastForward(options *Options) error
+	// Rebase rebases local commits of the current branch onto its upstream, aborting on conflicts
+	Rebase(options *Options) error
+	// Checkout switches the worktree to options.Branch
+	Checkout(options *Options) error
+	// Branch lists local branches
+	Branch(options *Options) (output string, err error)
+	// LocalBranches returns every local branch with the commits it has which are missing on its upstream
+	LocalBranches(options *Options) ([]BranchState, error)
+	// SymbolicRef shows information about remote repository (default branch etc.)
+	SymbolicRef(options *Options) (output string, err error)
+	// CurrentBranch returns the checked out branch, "HEAD" when detached
+	CurrentBranch(options *Options) (branch string, err error)
+	// AheadBehind counts commits of the current branch missing on its upstream and vice versa
+	AheadBehind(options *Options) (ahead int, behind int, err error)
+	// State collects branch, upstream and worktree information in a single pass
+	State(options *Options) (*RepoState, error)
+	// LastCommit returns the commit date of HEAD, zero for repositories without commits
+	LastCommit(options *Options) (time.Time, error)
+}
+
+// New returns the backend with the given name
+func New(name string) (Backend, error) {
+	switch name {
+	case NameCLI, "":
+		return NewCLI(), nil
+	case NameGoGit:
+		return NewGoGit(), nil
+	default:
+		return nil, fmt.Errorf("unknown backend '%s' (available: %s, %s)", name, NameCLI, NameGoGit)
+	}
+}
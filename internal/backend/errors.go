@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"aww/exec"
+)
+
+// Kinds of git failures recognised from the stderr of git
+var (
+	ErrAuthFailed     = errors.New("authentication failed")
+	ErrRepoNotFound   = errors.New("repository not found")
+	ErrNonFastForward = errors.New("non-fast-forward update rejected")
+	ErrMergeConflict  = errors.New("merge conflict")
+	ErrDirtyWorktree  = errors.New("worktree has local changes")
+	ErrBranchNotFound = errors.New("branch not found")
+)
+
+// GitError is a git failure classified into one of the known kinds
+type GitError struct {
+	Kind error  // One of the Err* kinds of this package
+	Hint string // Human readable remediation
+	Err  error  // Underlying error, usually *exec.RunError
+}
+
+// Error implements the error interface for GitError.
+func (e *GitError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of the error, so errors.Is(err, ErrAuthFailed) works.
+func (e *GitError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// classifier maps stderr messages of git to an error kind
+type classifier struct {
+	kind     error
+	hint     string
+	messages []string
+}
+
+// Order matters, the first matching classifier wins
+var classifiers = []classifier{
+	{
+		kind: ErrAuthFailed,
+		hint: "check that your SSH key is loaded (ssh-add -l), that the host is in ~/.ssh/known_hosts or that your credentials/token are valid for this host (aww never prompts for them)",
+		messages: []string{
+			"permission denied (publickey",
+			"authentication failed",
+			"could not read username",
+			"host key verification failed",
+			"access denied",
+			"invalid username or password",
+		},
+	},
+	{
+		kind: ErrRepoNotFound,
+		hint: "verify the url in the repositories file, the repository may have been renamed, moved or deleted",
+		messages: []string{
+			"repository not found",
+			"does not appear to be a git repository",
+			"could not be found",
+			"project you were looking for could not be found",
+		},
+	},
+	{
+		kind: ErrBranchNotFound,
+		hint: "the branch doesn't exist locally nor on the remote, run 'git fetch' or check the branch name",
+		messages: []string{
+			"did not match any file(s) known to git",
+			"couldn't find remote ref",
+			"invalid reference",
+			"not a valid branch name",
+			"not found in upstream",
+		},
+	},
+	{
+		kind: ErrNonFastForward,
+		hint: "the remote has commits you don't have locally, pull (or sync) the repository before pushing",
+		messages: []string{
+			"non-fast-forward",
+			"fetch first",
+			"updates were rejected",
+			"not possible to fast-forward",
+			"diverging branches",
+		},
+	},
+	{
+		kind: ErrMergeConflict,
+		hint: "resolve the conflicts in the repository, then commit the result (or abort the merge/rebase)",
+		messages: []string{
+			"automatic merge failed",
+			"merge conflict",
+			"unmerged files",
+			"fix conflicts",
+			"could not apply",
+		},
+	},
+	{
+		kind: ErrDirtyWorktree,
+		hint: "commit or stash the local changes before running the command again",
+		messages: []string{
+			"would be overwritten by",
+			"please commit your changes or stash them",
+			"you have unstaged changes",
+			"your index contains uncommitted changes",
+		},
+	},
+}
+
+// Classify wraps a failed git command into a *GitError when its stderr matches a known
+// kind of failure, otherwise err is returned unchanged.
+func Classify(err error) error {
+	var runErr *exec.RunError
+	if err == nil || !errors.As(err, &runErr) {
+		return err
+	}
+
+	var gitErr *GitError
+	if errors.As(err, &gitErr) {
+		return err
+	}
+
+	stderr := strings.ToLower(runErr.Stderr)
+	for _, c := range classifiers {
+		for _, msg := range c.messages {
+			if strings.Contains(stderr, msg) {
+				return &GitError{Kind: c.kind, Hint: c.hint, Err: err}
+			}
+		}
+	}
+
+	return err
+}
+
+// Hint returns the remediation hint of a classified git error, or empty string
+func Hint(err error) string {
+	var gitErr *GitError
+	if errors.As(err, &gitErr) {
+		return gitErr.Hint
+	}
+	return ""
+}
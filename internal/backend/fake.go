@@ -0,0 +1,464 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeRepository is the in-memory state of a repository managed by the Fake backend
+type FakeRepository struct {
+	Url           string
+	Branch        string         // Checked out branch
+	DefaultBranch string         // Branch origin/HEAD points to
+	Branches      []string       // Local branches
+	Changes       []string       // Files with uncommitted changes
+	Untracked     []string       // Untracked files
+	Staged        bool           // Changes were added to the index
+	Unpushed      []string       // Messages of commits not pushed yet
+	Pushed        []string       // Messages of commits pushed to the remote
+	Behind        int            // Number of remote commits missing locally
+	Stashes       int            // Number of stashed changes
+	BranchesAhead map[string]int // Unpushed commits of the other local branches, -1 for a branch without upstream
+	NoUpstream    bool           // The checked out branch has no upstream
+	Remote        string         // Remote of the upstream, origin when empty
+	Fetched       []string       // Fetched remotes, empty for the default remote
+	LastCommit    time.Time      // Date of the last commit
+	Depth         int            // History depth of a shallow clone, 0 for the full history
+	Filter        string         // Partial clone filter
+	Sparse        []string       // Sparse checkout patterns
+	Reference     string         // Repository the objects were borrowed from
+	Dissociate    bool           // Borrowed objects were copied
+	Bare          bool           // Mirror without a worktree
+}
+
+// Fake is an in-memory Backend for tests, repositories are keyed by their directory.
+// Clone also creates the target directory, as the commands check the presence of repositories on disk.
+type Fake struct {
+	mu     sync.Mutex
+	Repos  map[string]*FakeRepository
+	Errors map[string]error // Errors returned by operations, keyed by "<operation> <dir>" (e.g. "push /tmp/repo")
+	Calls  []string         // Performed operations in the "<operation> <dir>" form
+}
+
+var _ Backend = (*Fake)(nil)
+
+// NewFake returns a Fake backend with the given repositories
+func NewFake(repos map[string]*FakeRepository) *Fake {
+	if repos == nil {
+		repos = map[string]*FakeRepository{}
+	}
+	return &Fake{
+		Repos:  repos,
+		Errors: map[string]error{},
+	}
+}
+
+// call records the operation and returns the repository and the injected error
+func (f *Fake) call(op string, options *Options) (*FakeRepository, error) {
+	key := op + " " + options.Dir
+	f.Calls = append(f.Calls, key)
+
+	if err := f.Errors[key]; err != nil {
+		return nil, err
+	}
+
+	repo, ok := f.Repos[options.Dir]
+	if !ok && op != "clone" {
+		return nil, fmt.Errorf("%s: %w", options.Dir, ErrRepoNotFound)
+	}
+	return repo, nil
+}
+
+// Clone creates a new repository with a single default branch
+func (f *Fake) Clone(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.call("clone", options); err != nil {
+		return err
+	}
+	if _, ok := f.Repos[options.Dir]; ok {
+		return fmt.Errorf("destination path '%s' already exists", options.Dir)
+	}
+
+	branch := options.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	f.Repos[options.Dir] = &FakeRepository{
+		Url:           options.Url,
+		Branch:        branch,
+		DefaultBranch: branch,
+		Branches:      []string{branch},
+		Depth:         options.Depth,
+		Filter:        options.Filter,
+		Sparse:        options.Sparse,
+		Dissociate:    options.Dissociate,
+	}
+	if _, ok := f.Repos[options.Reference]; ok {
+		f.Repos[options.Dir].Reference = options.Reference
+	}
+	if options.Progress != nil {
+		options.Progress(Progress{Phase: "Receiving objects", Percent: 100, Current: 1, Total: 1})
+	}
+
+	return os.MkdirAll(options.Dir, 0755)
+}
+
+// Mirror creates a bare repository, an existing one is fetched
+func (f *Fake) Mirror(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := "mirror " + options.Dir
+	f.Calls = append(f.Calls, key)
+	if err := f.Errors[key]; err != nil {
+		return err
+	}
+	if _, ok := f.Repos[options.Dir]; ok {
+		return nil
+	}
+
+	f.Repos[options.Dir] = &FakeRepository{Url: options.Url, Bare: true}
+	return os.MkdirAll(options.Dir, 0755)
+}
+
+// GC only records the call
+func (f *Fake) GC(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err := f.call("gc", options)
+	return err
+}
+
+// Unshallow deepens a shallow repository by options.Depth, or completes its history
+func (f *Fake) Unshallow(options *Options) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("unshallow", options)
+	if err != nil {
+		return false, err
+	}
+	if repo.Depth == 0 {
+		return false, nil
+	}
+
+	if options.Depth > 0 {
+		repo.Depth += options.Depth
+	} else {
+		repo.Depth = 0
+	}
+	return true, nil
+}
+
+// Status returns the changes in the short format
+func (f *Fake) Status(options *Options) (output string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("status", options)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	for _, file := range repo.Changes {
+		if repo.Staged {
+			builder.WriteString("M  " + file + "\n")
+		} else {
+			builder.WriteString(" M " + file + "\n")
+		}
+	}
+	return builder.String(), nil
+}
+
+// Cherry returns the unpushed commits in the "git cherry -v" format
+func (f *Fake) Cherry(options *Options) (output string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("cherry", options)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	for i, msg := range repo.Unpushed {
+		fmt.Fprintf(&builder, "+ %040x %s\n", i+1, msg)
+	}
+	return builder.String(), nil
+}
+
+// Push moves the unpushed commits to the remote
+func (f *Fake) Push(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("push", options)
+	if err != nil {
+		return err
+	}
+
+	repo.Pushed = append(repo.Pushed, repo.Unpushed...)
+	repo.Unpushed = nil
+	return nil
+}
+
+// Commit turns the staged changes into an unpushed commit
+func (f *Fake) Commit(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if options.CommitMsg == "" {
+		return fmt.Errorf("commit message cannot be empty")
+	}
+
+	repo, err := f.call("commit", options)
+	if err != nil {
+		return err
+	}
+	if !repo.Staged || len(repo.Changes) == 0 {
+		return fmt.Errorf("nothing to commit, working tree clean")
+	}
+
+	repo.Unpushed = append(repo.Unpushed, options.CommitMsg)
+	repo.Changes = nil
+	repo.Staged = false
+	return nil
+}
+
+// Add stages all changes
+func (f *Fake) Add(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("add", options)
+	if err != nil {
+		return err
+	}
+
+	repo.Staged = true
+	return nil
+}
+
+// Pull only records the call, the fake remote never has new commits
+func (f *Fake) Pull(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err := f.call("pull", options)
+	return err
+}
+
+// Fetch only records the call
+func (f *Fake) Fetch(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("fetch", options)
+	if err != nil {
+		return err
+	}
+
+	repo.Fetched = append(repo.Fetched, options.Remote)
+	return nil
+}
+
+// FastForward takes the remote commits, failing when the branch has local commits
+func (f *Fake) FastForward(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("fast-forward", options)
+	if err != nil {
+		return err
+	}
+	if len(repo.Unpushed) > 0 && repo.Behind > 0 {
+		return &GitError{Kind: ErrNonFastForward, Err: fmt.Errorf("not possible to fast-forward, aborting")}
+	}
+
+	repo.Behind = 0
+	return nil
+}
+
+// Rebase takes the remote commits and keeps the unpushed ones on top
+func (f *Fake) Rebase(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("rebase", options)
+	if err != nil {
+		return err
+	}
+
+	repo.Behind = 0
+	return nil
+}
+
+// Checkout switches to an existing local branch
+func (f *Fake) Checkout(options *Options) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("checkout", options)
+	if err != nil {
+		return err
+	}
+
+	for _, branch := range repo.Branches {
+		if branch == options.Branch {
+			repo.Branch = branch
+			return nil
+		}
+	}
+	return &GitError{
+		Kind: ErrBranchNotFound,
+		Err:  fmt.Errorf("pathspec '%s' did not match any file(s) known to git", options.Branch),
+	}
+}
+
+// Branch lists local branches in the "git branch" format
+func (f *Fake) Branch(options *Options) (output string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("branch", options)
+	if err != nil {
+		return "", err
+	}
+
+	branches := append([]string(nil), repo.Branches...)
+	sort.Strings(branches)
+
+	var builder strings.Builder
+	for _, branch := range branches {
+		if branch == repo.Branch {
+			builder.WriteString("* " + branch + "\n")
+		} else {
+			builder.WriteString("  " + branch + "\n")
+		}
+	}
+	return builder.String(), nil
+}
+
+// SymbolicRef returns the ref origin/HEAD points to
+func (f *Fake) SymbolicRef(options *Options) (output string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("symbolic-ref", options)
+	if err != nil {
+		return "", err
+	}
+
+	return "refs/remotes/origin/" + repo.DefaultBranch + "\n", nil
+}
+
+// CurrentBranch returns the checked out branch
+func (f *Fake) CurrentBranch(options *Options) (branch string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("current-branch", options)
+	if err != nil {
+		return "", err
+	}
+
+	return repo.Branch, nil
+}
+
+// AheadBehind returns the number of unpushed commits and FakeRepository.Behind
+func (f *Fake) AheadBehind(options *Options) (ahead int, behind int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("ahead-behind", options)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return len(repo.Unpushed), repo.Behind, nil
+}
+
+// LocalBranches returns the checked out branch and FakeRepository.BranchesAhead
+func (f *Fake) LocalBranches(options *Options) ([]BranchState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("branches", options)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := repo.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	branch := BranchState{Name: repo.Branch, Ahead: len(repo.Unpushed)}
+	if !repo.NoUpstream {
+		branch.Upstream = remote + "/" + repo.Branch
+	}
+	branches := []BranchState{branch}
+	for name, ahead := range repo.BranchesAhead {
+		branch := BranchState{Name: name, Upstream: remote + "/" + name, Ahead: ahead}
+		if ahead < 0 {
+			branch.Upstream, branch.Ahead = "", 0
+		}
+		branches = append(branches, branch)
+	}
+	sort.Slice(branches[1:], func(i, j int) bool { return branches[i+1].Name < branches[j+1].Name })
+	return branches, nil
+}
+
+// State builds the state from the fields of the repository
+func (f *Fake) State(options *Options) (*RepoState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("state", options)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := repo.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	state := &RepoState{
+		Branch:    repo.Branch,
+		Head:      fmt.Sprintf("%040x", len(repo.Pushed)+len(repo.Unpushed)),
+		Upstream:  remote + "/" + repo.Branch,
+		Ahead:     len(repo.Unpushed),
+		Behind:    repo.Behind,
+		Untracked: len(repo.Untracked),
+		Stashes:   repo.Stashes,
+	}
+	if repo.NoUpstream {
+		state.Upstream, state.Ahead, state.Behind = "", 0, 0
+	}
+	if repo.Staged {
+		state.Staged = len(repo.Changes)
+	} else {
+		state.Unstaged = len(repo.Changes)
+	}
+	return state, nil
+}
+
+// LastCommit returns FakeRepository.LastCommit
+func (f *Fake) LastCommit(options *Options) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, err := f.call("last-commit", options)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return repo.LastCommit, nil
+}
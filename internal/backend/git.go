@@ -1,128 +1,379 @@
 package backend
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"aww/exec"
 )
 
-type Options struct {
-	Url            string
-	Dir            string
-	Branch         string
-	CommitMsg      string // For commit message
-	Remote         string // For push/pull remote
-	AdditionalArgs []string
-}
-
-// GitBackend defines common git operations
-type GitBackend struct {
-	Clone       func(*Options) error
-	Status      func(options *Options) (output string, err error)
-	Cherry      func(options *Options) (output string, err error)
-	Push        func(options *Options) error
-	Commit      func(options *Options) error
-	Add         func(options *Options) error
-	Pull        func(options *Options) error
-	Checkout    func(options *Options) error
-	Branch      func(options *Options) (output string, err error)
-	SymbolicRef func(options *Options) (output string, err error)
-}
-
-// Git provides a GitBackend instance
-var Git = &GitBackend{
-	// Clone performs git clone operation
-	Clone: func(options *Options) error {
-		dir, _ := filepath.Split(options.Dir)
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return err
-		}
+// CLI is the Backend running the git binary found in PATH
+type CLI struct{}
 
-		args := []string{"clone"}
+var _ Backend = (*CLI)(nil)
 
-		if options.Branch != "" {
-			args = append(args, "--branch", options.Branch, "--single-branch")
-		}
+// NewCLI returns the git CLI backend
+func NewCLI() *CLI {
+	return &CLI{}
+}
 
-		args = append(args, options.Url, options.Dir)
+// Clone performs git clone operation
+func (g *CLI) Clone(options *Options) error {
+	// Remember whether the target exists, so a partial clone can be cleaned up
+	_, statErr := os.Stat(options.Dir)
+	created := errors.Is(statErr, os.ErrNotExist)
 
-		_, err = exec.New().Silent().Go("git", args...)
+	dir, _ := filepath.Split(options.Dir)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
 		return err
-	},
+	}
 
-	// Status retrieves git status
-	Status: func(options *Options) (output string, err error) {
-		args := []string{"status"}
-		args = append(args, options.AdditionalArgs...)
+	args := []string{"clone"}
 
-		return exec.New().Dir(options.Dir).Silent().Output().Go("git", args...)
-	},
+	if options.Branch != "" {
+		args = append(args, "--branch", options.Branch)
+	}
+	if options.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if options.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(options.Depth))
+	}
+	if options.Filter != "" {
+		args = append(args, "--filter", options.Filter)
+	}
+	if len(options.Sparse) > 0 {
+		args = append(args, "--sparse")
+	}
+	if options.Reference != "" {
+		args = append(args, "--reference-if-able", options.Reference)
+		if options.Dissociate {
+			args = append(args, "--dissociate")
+		}
+	}
 
-	// Cherry verify if repository has a unpushed commits
-	Cherry: func(options *Options) (output string, err error) {
-		args := []string{"cherry"}
-		args = append(args, options.AdditionalArgs...)
+	if options.Progress != nil {
+		// Git only reports progress to terminals unless asked
+		args = append(args, "--progress")
+	}
 
-		return exec.New().Dir(options.Dir).Silent().Output().Go("git", args...)
-	},
+	// Urls and paths starting with '-' aren't taken as options
+	args = append(args, "--", options.Url, options.Dir)
 
-	// Push pushes the local branch to the remote
-	Push: func(options *Options) error {
-		args := []string{"push", options.Remote}
-		if options.Branch != "" {
-			args = append(args, options.Branch)
+	return withRetry(options, OpClone, func(ctx context.Context) error {
+		runner := exec.New().Context(ctx).NonInteractive().Silent()
+		if options.Progress != nil {
+			runner.Writer(&progressWriter{report: options.Progress})
+		}
+		_, err := runner.Go("git", args...)
+		if err == nil && len(options.Sparse) > 0 {
+			err = sparseCheckout(ctx, options)
+		}
+		if err != nil && created {
+			// Remove the half cloned directory (e.g. git was killed on cancel or timeout)
+			if rmErr := os.RemoveAll(options.Dir); rmErr != nil {
+				return errors.Join(err, rmErr)
+			}
 		}
-		_, err := exec.New().Dir(options.Dir).Silent().Go("git", args...)
 		return err
-	},
+	})
+}
 
-	// Commit commits changes with the provided message
-	Commit: func(options *Options) error {
-		if options.CommitMsg == "" {
-			return fmt.Errorf("commit message cannot be empty")
+// sparseCheckout restricts the worktree of a sparse clone to options.Sparse. Plain directories
+// use the faster cone mode, patterns with wildcards or negations need the full pattern syntax.
+func sparseCheckout(ctx context.Context, options *Options) error {
+	args := []string{"sparse-checkout", "set"}
+	for _, pattern := range options.Sparse {
+		if strings.ContainsAny(pattern, "*?[!") {
+			args = append(args, "--no-cone")
+			break
 		}
+	}
+	args = append(args, options.Sparse...)
+
+	_, err := exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Go("git", args...)
+	return err
+}
 
-		args := []string{"commit", "-m", options.CommitMsg}
-		_, err := exec.New().Dir(options.Dir).Silent().Go("git", args...)
+// Mirror creates a bare mirror of options.Url in options.Dir, or fetches the new objects of an existing one.
+// Branches and tags are fetched without pruning, so clones borrowing objects of the mirror stay complete
+// when branches are deleted or force-pushed upstream.
+func (g *CLI) Mirror(options *Options) error {
+	if _, err := os.Stat(options.Dir); err == nil {
+		return withRetry(options, OpFetch, func(ctx context.Context) error {
+			_, err := exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Go("git", "fetch", "--tags", "--force", "origin")
+			return err
+		})
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	parent, name := filepath.Split(options.Dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
 		return err
-	},
+	}
 
-	// Add add changes
-	Add: func(options *Options) error {
-		args := []string{"add", "."}
-		_, err := exec.New().Dir(options.Dir).Silent().Go("git", args...)
+	// The mirror is cloned next to its final path and renamed, so a partial mirror is never borrowed from
+	temp, err := os.MkdirTemp(parent, "."+name+".*.tmp")
+	if err != nil {
 		return err
-	},
+	}
+	defer os.RemoveAll(temp)
 
-	// Pull pulls the latest changes from the remote
-	Pull: func(options *Options) error {
-		args := []string{"pull", options.Remote}
-		if options.Branch != "" {
-			args = append(args, options.Branch)
+	err = withRetry(options, OpClone, func(ctx context.Context) error {
+		if err := os.RemoveAll(temp); err != nil {
+			return err
 		}
-		_, err := exec.New().Dir(options.Dir).Silent().Go("git", args...)
+		_, err := exec.New().Context(ctx).NonInteractive().Silent().Go("git", "clone", "--bare", "--", options.Url, temp)
+		return err
+	})
+	if err != nil {
 		return err
-	},
-	Checkout: func(options *Options) error {
-		args := []string{"checkout", options.Branch}
+	}
 
-		_, err := exec.New().Dir(options.Dir).Silent().Go("git", args...)
+	// Bare clones don't fetch anything by default
+	err = gitRun(&Options{Context: options.Context, Dir: temp}, OpLocal, "config", "remote.origin.fetch", "+refs/heads/*:refs/heads/*")
+	if err != nil {
 		return err
-	},
-	Branch: func(options *Options) (output string, err error) {
-		args := []string{"branch"}
-		args = append(args, options.AdditionalArgs...)
+	}
 
-		return exec.New().Dir(options.Dir).Silent().Go("git", args...)
-	},
+	err = os.Rename(temp, options.Dir)
+	if err != nil {
+		if _, statErr := os.Stat(options.Dir); statErr == nil {
+			// Created meanwhile by another aww process
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// GC compacts the objects of the repository in options.Dir, unreachable objects are kept as other
+// repositories may borrow them. Repacking large repositories is slow, it gets the timeout of clones.
+func (g *CLI) GC(options *Options) error {
+	return gitRun(options, OpClone, "gc", "--quiet", "--prune=never")
+}
 
-	// SymbolicRef shows information about remote repository (default branch etc.)
-	SymbolicRef: func(options *Options) (output string, err error) {
-		args := []string{"symbolic-ref", "refs/remotes/origin/HEAD"}
+// Unshallow fetches the missing history of a shallow clone, options.Depth deepens it by a number of commits
+func (g *CLI) Unshallow(options *Options) (bool, error) {
+	output, err := gitOutput(options, OpLocal, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(output) != "true" {
+		return false, nil
+	}
 
-		return exec.New().Dir(options.Dir).Silent().Output().Go("git", args...)
-	},
+	args := []string{"fetch"}
+	if options.Depth > 0 {
+		args = append(args, "--deepen="+strconv.Itoa(options.Depth))
+	} else {
+		args = append(args, "--unshallow")
+	}
+	if options.Remote != "" {
+		args = append(args, options.Remote)
+	}
+	err = withRetry(options, OpFetch, func(ctx context.Context) error {
+		_, err := exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Go("git", args...)
+		return err
+	})
+	return err == nil, err
+}
+
+// Status retrieves git status
+func (g *CLI) Status(options *Options) (output string, err error) {
+	args := []string{"status"}
+	args = append(args, options.AdditionalArgs...)
+
+	return gitOutput(options, OpLocal, args...)
+}
+
+// Cherry verify if repository has a unpushed commits
+func (g *CLI) Cherry(options *Options) (output string, err error) {
+	args := []string{"cherry"}
+	args = append(args, options.AdditionalArgs...)
+
+	return gitOutput(options, OpLocal, args...)
+}
+
+// Push pushes the local branch to the remote
+func (g *CLI) Push(options *Options) error {
+	args := []string{"push"}
+	if options.Remote != "" {
+		args = append(args, options.Remote)
+	}
+	if options.Branch != "" {
+		args = append(args, options.Branch)
+	}
+	return withRetry(options, OpPush, func(ctx context.Context) error {
+		_, err := exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Go("git", args...)
+		return err
+	})
+}
+
+// Commit commits changes with the provided message
+func (g *CLI) Commit(options *Options) error {
+	if options.CommitMsg == "" {
+		return fmt.Errorf("commit message cannot be empty")
+	}
+
+	args := []string{"commit", "-m", options.CommitMsg}
+	return gitRun(options, OpLocal, args...)
+}
+
+// Add add changes
+func (g *CLI) Add(options *Options) error {
+	args := []string{"add", "."}
+	return gitRun(options, OpLocal, args...)
+}
+
+// Pull pulls the latest changes from the remote
+func (g *CLI) Pull(options *Options) error {
+	args := []string{"pull"}
+	if options.Remote != "" {
+		args = append(args, options.Remote)
+	}
+	if options.Branch != "" {
+		args = append(args, options.Branch)
+	}
+	return withRetry(options, OpPull, func(ctx context.Context) error {
+		_, err := exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Go("git", args...)
+		return err
+	})
+}
+
+// Fetch fetches options.Remote (or the default remote) and prunes deleted branches
+func (g *CLI) Fetch(options *Options) error {
+	args := []string{"fetch", "--prune"}
+	if options.Remote != "" {
+		args = append(args, options.Remote)
+	}
+	return withRetry(options, OpFetch, func(ctx context.Context) error {
+		_, err := exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Go("git", args...)
+		return err
+	})
+}
+
+// FastForward fast-forwards the current branch to its upstream, failing if it's not possible
+func (g *CLI) FastForward(options *Options) error {
+	return gitRun(options, OpLocal, "merge", "--ff-only", "@{upstream}")
+}
+
+// Rebase rebases local commits of the current branch onto its upstream, aborting on conflicts
+func (g *CLI) Rebase(options *Options) error {
+	err := gitRun(options, OpLocal, "rebase", "@{upstream}")
+	if err != nil {
+		// Leave the repository as it was before the rebase
+		if abortErr := gitRun(options, OpLocal, "rebase", "--abort"); abortErr != nil {
+			return errors.Join(err, abortErr)
+		}
+	}
+	return err
+}
+
+// Checkout switches the worktree to options.Branch
+func (g *CLI) Checkout(options *Options) error {
+	args := []string{"checkout", options.Branch}
+
+	return gitRun(options, OpLocal, args...)
+}
+
+// Branch lists local branches
+func (g *CLI) Branch(options *Options) (output string, err error) {
+	args := []string{"branch"}
+	args = append(args, options.AdditionalArgs...)
+
+	return gitOutput(options, OpLocal, args...)
+}
+
+// branchesFormat prints the name, the upstream and the tracking state of branches separated by NUL
+const branchesFormat = "%(refname:short)%00%(upstream:short)%00%(upstream:track)"
+
+// LocalBranches returns every local branch with the commits it has which are missing on its upstream
+func (g *CLI) LocalBranches(options *Options) ([]BranchState, error) {
+	output, err := gitOutput(options, OpLocal, "for-each-ref", "--format="+branchesFormat, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseBranches(output)
+}
+
+// SymbolicRef shows information about remote repository (default branch etc.)
+func (g *CLI) SymbolicRef(options *Options) (output string, err error) {
+	args := []string{"symbolic-ref", "refs/remotes/origin/HEAD"}
+
+	return gitOutput(options, OpLocal, args...)
+}
+
+// CurrentBranch returns the checked out branch, "HEAD" when detached
+func (g *CLI) CurrentBranch(options *Options) (branch string, err error) {
+	output, err := gitOutput(options, OpLocal, "rev-parse", "--abbrev-ref", "HEAD")
+	return strings.TrimSpace(output), err
+}
+
+// AheadBehind counts commits of the current branch missing on its upstream and vice versa
+func (g *CLI) AheadBehind(options *Options) (ahead int, behind int, err error) {
+	output, err := gitOutput(options, OpLocal, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = fmt.Sscanf(output, "%d %d", &ahead, &behind)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q: %w", output, err)
+	}
+	return ahead, behind, nil
+}
+
+// State collects the repository state with a single "git status --porcelain=v2"
+func (g *CLI) State(options *Options) (*RepoState, error) {
+	output, err := gitOutput(options, OpLocal, "status", "--porcelain=v2", "--branch", "--show-stash")
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseStatusV2(output)
+}
+
+// LastCommit returns the commit date of HEAD, zero for repositories without commits
+func (g *CLI) LastCommit(options *Options) (time.Time, error) {
+	output, err := gitOutput(options, OpLocal, "log", "-1", "--format=%ct")
+	if err != nil {
+		var runErr *exec.RunError
+		if errors.As(err, &runErr) && strings.Contains(runErr.Stderr, "does not have any commits yet") {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	timestamp, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected log output %q: %w", output, err)
+	}
+	return time.Unix(timestamp, 0), nil
+}
+
+// gitRun runs a git command in options.Dir within the timeout of the operation
+func gitRun(options *Options, op Operation, args ...string) error {
+	return withTimeout(options, op, func(ctx context.Context) error {
+		_, err := exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Go("git", args...)
+		return err
+	})
+}
+
+// gitOutput runs a git command in options.Dir within the timeout of the operation and returns its stdout
+func gitOutput(options *Options, op Operation, args ...string) (output string, err error) {
+	err = withTimeout(options, op, func(ctx context.Context) error {
+		output, err = exec.New().Context(ctx).NonInteractive().Dir(options.Dir).Silent().Output().Go("git", args...)
+		return err
+	})
+	return output, err
 }
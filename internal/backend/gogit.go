@@ -0,0 +1,453 @@
+package backend
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// GoGit is a Backend answering read-only queries (status, ahead/behind, branches, refs)
+// in-process with go-git, without forking git. Mutating operations fall back to the CLI.
+//
+// go-git's worktree status only honours the .gitignore files of the repository and .git/info/exclude,
+// not core.excludesFile or the global excludes, so it can count more untracked files than the CLI backend.
+type GoGit struct {
+	*CLI
+}
+
+var _ Backend = (*GoGit)(nil)
+
+// NewGoGit returns the go-git backend
+func NewGoGit() *GoGit {
+	return &GoGit{CLI: NewCLI()}
+}
+
+// open opens the repository in options.Dir
+func (g *GoGit) open(options *Options) (*gogit.Repository, error) {
+	if options.Context != nil && options.Context.Err() != nil {
+		return nil, options.Context.Err()
+	}
+
+	repo, err := gogit.PlainOpenWithOptions(options.Dir, &gogit.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository %s: %w", options.Dir, err)
+	}
+	return repo, nil
+}
+
+// Status returns the changes in the "git status -s" format, AdditionalArgs are ignored
+func (g *GoGit) Status(options *Options) (output string, err error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return "", err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(status))
+	for path, file := range status {
+		if file.Staging == gogit.Unmodified && file.Worktree == gogit.Unmodified {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var builder strings.Builder
+	for _, path := range paths {
+		file := status[path]
+		if file.Staging == gogit.Renamed {
+			path = fmt.Sprintf("%s -> %s", path, file.Extra)
+		}
+		fmt.Fprintf(&builder, "%c%c %s\n", file.Staging, file.Worktree, path)
+	}
+	return builder.String(), nil
+}
+
+// Cherry lists commits of the current branch missing on its upstream in the "git cherry -v" format.
+// Unlike git, commits are not compared by patch-id, so every listed commit is marked with "+".
+func (g *GoGit) Cherry(options *Options) (output string, err error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return "", err
+	}
+
+	head, upstream, err := headAndUpstream(repo)
+	if err != nil {
+		return "", err
+	}
+
+	ahead, _, err := leftRight(repo, head.Hash(), upstream.Hash())
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	// git cherry prints the oldest commit first
+	for i := len(ahead) - 1; i >= 0; i-- {
+		subject, _, _ := strings.Cut(ahead[i].Message, "\n")
+		fmt.Fprintf(&builder, "+ %s %s\n", ahead[i].Hash, subject)
+	}
+	return builder.String(), nil
+}
+
+// Branch lists local branches in the "git branch" format, AdditionalArgs are ignored
+func (g *GoGit) Branch(options *Options) (output string, err error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return "", err
+	}
+
+	current := ""
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		current = head.Name().Short()
+	}
+
+	iter, err := repo.Branches()
+	if err != nil {
+		return "", err
+	}
+
+	var branches []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		branches = append(branches, ref.Name().Short())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(branches)
+
+	var builder strings.Builder
+	for _, branch := range branches {
+		if branch == current {
+			builder.WriteString("* " + branch + "\n")
+		} else {
+			builder.WriteString("  " + branch + "\n")
+		}
+	}
+	return builder.String(), nil
+}
+
+// SymbolicRef returns the ref origin/HEAD points to
+func (g *GoGit) SymbolicRef(options *Options) (output string, err error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := repo.Reference(plumbing.NewRemoteHEADReferenceName("origin"), false)
+	if err != nil {
+		return "", fmt.Errorf("ref refs/remotes/origin/HEAD is not a symbolic ref: %w", err)
+	}
+	if ref.Type() != plumbing.SymbolicReference {
+		return "", fmt.Errorf("ref refs/remotes/origin/HEAD is not a symbolic ref")
+	}
+
+	return ref.Target().String() + "\n", nil
+}
+
+// CurrentBranch returns the checked out branch, "HEAD" when detached
+func (g *GoGit) CurrentBranch(options *Options) (branch string, err error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target().IsBranch() {
+		return head.Target().Short(), nil
+	}
+
+	return "HEAD", nil
+}
+
+// AheadBehind counts commits of the current branch missing on its upstream and vice versa
+func (g *GoGit) AheadBehind(options *Options) (ahead int, behind int, err error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	head, upstream, err := headAndUpstream(repo)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	onlyHead, onlyUpstream, err := leftRight(repo, head.Hash(), upstream.Hash())
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(onlyHead), len(onlyUpstream), nil
+}
+
+// State collects the repository state in-process
+func (g *GoGit) State(options *Options) (*RepoState, error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &RepoState{}
+
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, err
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		state.Branch = head.Target().Short()
+	} else {
+		state.Detached = true
+	}
+
+	resolved, err := repo.Head()
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		state.Head = resolved.Hash().String()
+
+		// Upstream and ahead/behind are only known for branches with a configured upstream
+		if local, upstream, err := headAndUpstream(repo); err == nil {
+			state.Upstream = upstream.Name().Short()
+			ahead, behind, err := leftRight(repo, local.Hash(), upstream.Hash())
+			if err != nil {
+				return nil, err
+			}
+			state.Ahead, state.Behind = len(ahead), len(behind)
+		}
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range status {
+		switch {
+		case file.Staging == gogit.UpdatedButUnmerged || file.Worktree == gogit.UpdatedButUnmerged:
+			state.Conflicts++
+		case file.Worktree == gogit.Untracked:
+			// Includes files only ignored by the global excludes, see GoGit
+			state.Untracked++
+		default:
+			if file.Staging != gogit.Unmodified {
+				state.Staged++
+			}
+			if file.Worktree != gogit.Unmodified {
+				state.Unstaged++
+			}
+		}
+	}
+
+	state.Stashes, err = stashCount(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// LastCommit returns the commit date of HEAD, zero for repositories without commits
+func (g *GoGit) LastCommit(options *Options) (time.Time, error) {
+	repo, err := g.open(options)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return commit.Committer.When, nil
+}
+
+// stashCount counts the entries of the stash reflog, go-git has no stash support
+func stashCount(repo *gogit.Repository) (int, error) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return 0, nil
+	}
+
+	file, err := storage.Filesystem().Open(filepath.Join("logs", "refs", "stash"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// headAndUpstream resolves HEAD and the upstream of the current branch
+func headAndUpstream(repo *gogit.Repository) (head *plumbing.Reference, upstream *plumbing.Reference, err error) {
+	head, err = repo.Head()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !head.Name().IsBranch() {
+		return nil, nil, fmt.Errorf("HEAD is detached, no upstream")
+	}
+
+	branch, err := repo.Branch(head.Name().Short())
+	if err != nil || branch.Remote == "" || branch.Merge == "" {
+		return nil, nil, fmt.Errorf("no upstream configured for branch '%s'", head.Name().Short())
+	}
+
+	name := branch.Merge
+	if branch.Remote != "." {
+		name = plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short())
+	}
+
+	upstream, err = repo.Reference(name, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("upstream %s of branch '%s' not found: %w", name, head.Name().Short(), err)
+	}
+	return head, upstream, nil
+}
+
+const (
+	sideLeft  uint8 = 1
+	sideRight uint8 = 2
+	sideBoth        = sideLeft | sideRight
+)
+
+// leftRight returns the commits reachable only from left and only from right, newest first,
+// like "git rev-list --left-right left...right". History is walked by commit date until only
+// commits reachable from both sides are left, so common history is not traversed.
+func leftRight(repo *gogit.Repository, left, right plumbing.Hash) (onlyLeft []*object.Commit, onlyRight []*object.Commit, err error) {
+	flags := map[plumbing.Hash]uint8{}
+	processed := map[plumbing.Hash]uint8{}
+	commits := map[plumbing.Hash]*object.Commit{}
+	queue := &commitQueue{}
+	// Number of times every commit is queued and of queued entries not yet reachable from both
+	// sides, the walk stops when only common history is left
+	queued := map[plumbing.Hash]int{}
+	pending := 0
+
+	push := func(hash plumbing.Hash, side uint8) error {
+		if flags[hash]&side == side {
+			return nil
+		}
+		flags[hash] |= side
+		if flags[hash] == sideBoth {
+			pending -= queued[hash]
+		} else {
+			pending++
+		}
+		queued[hash]++
+
+		commit, ok := commits[hash]
+		if !ok {
+			var err error
+			commit, err = repo.CommitObject(hash)
+			if err != nil {
+				return err
+			}
+			commits[hash] = commit
+		}
+		heap.Push(queue, commit)
+		return nil
+	}
+
+	if err := push(left, sideLeft); err != nil {
+		return nil, nil, err
+	}
+	if err := push(right, sideRight); err != nil {
+		return nil, nil, err
+	}
+
+	for queue.Len() > 0 && pending > 0 {
+		commit := heap.Pop(queue).(*object.Commit)
+		side := flags[commit.Hash]
+		queued[commit.Hash]--
+		if side != sideBoth {
+			pending--
+		}
+		if processed[commit.Hash] == side {
+			continue
+		}
+		processed[commit.Hash] = side
+
+		for _, parent := range commit.ParentHashes {
+			if err := push(parent, side); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for hash, side := range flags {
+		switch side {
+		case sideLeft:
+			onlyLeft = append(onlyLeft, commits[hash])
+		case sideRight:
+			onlyRight = append(onlyRight, commits[hash])
+		}
+	}
+	sortNewestFirst(onlyLeft)
+	sortNewestFirst(onlyRight)
+
+	return onlyLeft, onlyRight, nil
+}
+
+func sortNewestFirst(commits []*object.Commit) {
+	sort.Slice(commits, func(i, j int) bool {
+		return commits[i].Committer.When.After(commits[j].Committer.When)
+	})
+}
+
+// commitQueue is a max-heap of commits ordered by commit date
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
@@ -0,0 +1,57 @@
+package backend
+
+import (
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoGitAheadBehind(t *testing.T) {
+	dir := t.TempDir()
+	url := bareRepository(t)
+	local, other := filepath.Join(dir, "local"), filepath.Join(dir, "other")
+	git := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-c", "user.name=aww", "-c", "user.email=aww@example.com"}, args...)
+		if output, err := osexec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	commit := func(repo, message string) {
+		t.Helper()
+		git("-C", repo, "commit", "-q", "--allow-empty", "-m", message)
+	}
+
+	git("clone", "-q", url, local)
+	git("clone", "-q", url, other)
+
+	// Two commits pushed by someone else
+	commit(other, "remote 1")
+	commit(other, "remote 2")
+	git("-C", other, "push", "-q", "origin", "HEAD")
+
+	// Three local commits, one of them merging a side branch
+	commit(local, "local 1")
+	git("-C", local, "checkout", "-q", "-b", "side", "HEAD~1")
+	commit(local, "side")
+	git("-C", local, "checkout", "-q", "-")
+	git("-C", local, "merge", "-q", "--no-ff", "-m", "merge side", "side")
+	git("-C", local, "fetch", "-q")
+
+	options := &Options{Dir: local}
+	wantAhead, wantBehind, err := NewCLI().AheadBehind(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantAhead != 3 || wantBehind != 2 {
+		t.Fatalf("git counts %d ahead, %d behind, want 3 and 2", wantAhead, wantBehind)
+	}
+
+	ahead, behind, err := NewGoGit().AheadBehind(options)
+	if err != nil {
+		t.Fatalf("AheadBehind() error = %v", err)
+	}
+	if ahead != wantAhead || behind != wantBehind {
+		t.Errorf("AheadBehind() = %d, %d, want %d, %d", ahead, behind, wantAhead, wantBehind)
+	}
+}
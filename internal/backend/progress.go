@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Progress is a progress line printed by git, e.g. "Receiving objects:  45% (450/1000), 1.20 MiB | 2.00 MiB/s"
+type Progress struct {
+	Phase   string // Counting objects, Receiving objects, Resolving deltas, Updating files...
+	Percent int
+	Current int
+	Total   int
+	Bytes   string // Received data and throughput, only reported while receiving objects
+}
+
+func (p Progress) String() string {
+	text := fmt.Sprintf("%s %d%% (%d/%d)", strings.ToLower(p.Phase), p.Percent, p.Current, p.Total)
+	if p.Bytes != "" {
+		text += ", " + p.Bytes
+	}
+	return text
+}
+
+// Progress line without the ", done." suffix of finished phases
+var progressPattern = regexp.MustCompile(`^(?:remote: )?([A-Za-z ]+):\s+(\d+)% \((\d+)/(\d+)\)(?:, (.+))?$`)
+
+// parseProgress parses a progress line, other lines (warnings, hints) are reported as not ok
+func parseProgress(line string) (Progress, bool) {
+	line = strings.TrimSuffix(strings.TrimSpace(line), ", done.")
+	match := progressPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Progress{}, false
+	}
+
+	progress := Progress{Phase: strings.TrimSpace(match[1]), Bytes: strings.TrimSpace(match[5])}
+	progress.Percent, _ = strconv.Atoi(match[2])
+	progress.Current, _ = strconv.Atoi(match[3])
+	progress.Total, _ = strconv.Atoi(match[4])
+	return progress, true
+}
+
+// progressWriter passes the progress lines written by git to report. Git updates a line in place
+// by ending it with '\r', lines may be split across writes.
+type progressWriter struct {
+	mu     sync.Mutex // stdout and stderr are copied by separate goroutines
+	report func(Progress)
+	line   []byte
+}
+
+// Write implements io.Writer, it never fails
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, b := range p {
+		if b != '\r' && b != '\n' {
+			w.line = append(w.line, b)
+			continue
+		}
+		if progress, ok := parseProgress(string(w.line)); ok {
+			w.report(progress)
+		}
+		w.line = w.line[:0]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,211 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"aww/exec"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Operation identifies a type of git operation, used for timeouts and retries
+type Operation string
+
+const (
+	OpClone Operation = "clone"
+	OpFetch Operation = "fetch"
+	OpPull  Operation = "pull"
+	OpPush  Operation = "push"
+	OpLocal Operation = "local" // Operations which don't touch the network (status, commit, checkout...)
+)
+
+// TimeoutSettings holds the maximum duration of every operation type, zero disables the timeout
+type TimeoutSettings struct {
+	Clone time.Duration
+	Fetch time.Duration
+	Pull  time.Duration
+	Push  time.Duration
+	Local time.Duration
+}
+
+// For returns the timeout of the given operation
+func (t *TimeoutSettings) For(op Operation) time.Duration {
+	switch op {
+	case OpClone:
+		return t.Clone
+	case OpFetch:
+		return t.Fetch
+	case OpPull:
+		return t.Pull
+	case OpPush:
+		return t.Push
+	default:
+		return t.Local
+	}
+}
+
+// RetryPolicy configures retries of network operations with exponential backoff
+type RetryPolicy struct {
+	Attempts     int64 // Total number of attempts, values below 1 mean a single attempt
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+var (
+	// Timeouts used by the git operations
+	Timeouts = TimeoutSettings{
+		Clone: 30 * time.Minute,
+		Fetch: 5 * time.Minute,
+		Pull:  5 * time.Minute,
+		Push:  5 * time.Minute,
+		Local: time.Minute,
+	}
+
+	// Retry is the policy used by network operations (clone, fetch, pull, push)
+	Retry = RetryPolicy{
+		Attempts:     3,
+		InitialDelay: 2 * time.Second,
+		MaxDelay:     30 * time.Second,
+	}
+)
+
+// Messages printed by git (or ssh/curl underneath) for failures which are worth another attempt
+var transientMessages = []string{
+	"connection reset",
+	"connection refused",
+	"timed out",
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"unexpected disconnect",
+	"rpc failed",
+	"kex_exchange_identification",
+	"ssh_exchange_identification",
+	"broken pipe",
+	"gnutls_handshake",
+	"http/2 stream",
+	"503 service unavailable",
+	"502 bad gateway",
+}
+
+// Messages of permanent failures not covered by the classifiers, checked before
+// transientMessages as git often prints "the remote end hung up unexpectedly" after the real cause
+var permanentMessages = []string{
+	"not found",
+	"already exists and is not an empty directory",
+	"rejected",
+}
+
+// timeoutError is returned by an attempt killed by the timeout of its operation while the caller still waits
+type timeoutError struct {
+	op      Operation
+	timeout time.Duration
+	err     error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("git %s timed out after %s: %v", e.op, e.timeout, e.err)
+}
+
+func (e *timeoutError) Unwrap() []error {
+	return []error{context.DeadlineExceeded, e.err}
+}
+
+// IsTransient reports whether err is a temporary failure that may succeed when retried
+func IsTransient(err error) bool {
+	// The caller gave up, either cancelled or past its own deadline
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// Classified failures (auth, not found, conflicts...) never fix themselves
+	var gitErr *GitError
+	if errors.As(err, &gitErr) {
+		return false
+	}
+
+	// An attempt killed by its own timeout is usually a hung connection, the next one may get through
+	var timeoutErr *timeoutError
+	if errors.As(err, &timeoutErr) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var runErr *exec.RunError
+	if !errors.As(err, &runErr) {
+		return false
+	}
+
+	stderr := strings.ToLower(runErr.Stderr)
+	for _, msg := range permanentMessages {
+		if strings.Contains(stderr, msg) {
+			return false
+		}
+	}
+	for _, msg := range transientMessages {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withTimeout runs fn with the timeout of the operation applied to the options context
+// and classifies the returned error
+func withTimeout(options *Options, op Operation, fn func(ctx context.Context) error) error {
+	parent := options.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	timeout := Timeouts.For(op)
+	if timeout <= 0 {
+		return Classify(fn(parent))
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	err := Classify(fn(ctx))
+	if err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &timeoutError{op: op, timeout: timeout, err: err}
+	}
+	return err
+}
+
+// withRetry runs a network operation, retrying transient failures with exponential backoff
+func withRetry(options *Options, op Operation, fn func(ctx context.Context) error) error {
+	parent := options.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	delay := Retry.InitialDelay
+	for attempt := int64(1); ; attempt++ {
+		err := withTimeout(options, op, fn)
+		if err == nil || attempt >= Retry.Attempts || parent.Err() != nil || !IsTransient(err) {
+			return err
+		}
+
+		log.Warn().Str("operation", string(op)).Str("dir", options.Dir).Int64("attempt", attempt).Dur("backoff", delay).Err(err).Msg("Retrying transient git failure")
+
+		select {
+		case <-time.After(delay):
+		case <-parent.Done():
+			return err
+		}
+
+		delay *= 2
+		if Retry.MaxDelay > 0 && delay > Retry.MaxDelay {
+			delay = Retry.MaxDelay
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aww/exec"
+)
+
+// errAny matches any error in test tables
+var errAny = errors.New("any error")
+
+// fastRetries makes retries quick and restores the policy, the timeouts and the command runner after the test
+func fastRetries(t *testing.T, attempts int64) {
+	t.Helper()
+
+	retry, timeouts, runner := Retry, Timeouts, exec.CommandRunner
+	t.Cleanup(func() {
+		Retry, Timeouts, exec.CommandRunner = retry, timeouts, runner
+	})
+	Retry = RetryPolicy{Attempts: attempts, InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+}
+
+// bareRepository creates a local repository with a commit and returns its file url
+func bareRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	work, bare := filepath.Join(dir, "work"), filepath.Join(dir, "repo.git")
+	for _, args := range [][]string{
+		{"init", "-q", work},
+		{"-C", work, "-c", "user.name=aww", "-c", "user.email=aww@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+		{"clone", "-q", "--bare", work, bare},
+	} {
+		if output, err := osexec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	return "file://" + bare
+}
+
+// failingRunner fails the first failures commands with the stderr, the next ones run for real
+func failingRunner(failures int32, stderr string, calls *atomic.Int32) func(cmd *osexec.Cmd) error {
+	return func(cmd *osexec.Cmd) error {
+		if calls.Add(1) <= failures {
+			fmt.Fprint(cmd.Stderr, stderr)
+			return errors.New("exit status 128")
+		}
+		return cmd.Run()
+	}
+}
+
+func TestCloneRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int64
+		failures  int32
+		stderr    string
+		wantCalls int32
+		wantErr   error // nil for success, errAny for any error
+	}{
+		{
+			name:      "success after transient failures",
+			attempts:  3,
+			failures:  2,
+			stderr:    "fatal: unable to access 'https://example.com/repo.git/': Connection reset by peer\n",
+			wantCalls: 3,
+		},
+		{
+			name:      "transient failures exhaust the attempts",
+			attempts:  3,
+			failures:  5,
+			stderr:    "fatal: the remote end hung up unexpectedly\n",
+			wantCalls: 3,
+			wantErr:   errAny,
+		},
+		{
+			name:      "single attempt",
+			attempts:  0,
+			failures:  1,
+			stderr:    "fatal: early EOF\n",
+			wantCalls: 1,
+			wantErr:   errAny,
+		},
+		{
+			name:      "auth failure isn't retried",
+			attempts:  3,
+			failures:  1,
+			stderr:    "git@example.com: Permission denied (publickey).\nfatal: Could not read from remote repository.\n",
+			wantCalls: 1,
+			wantErr:   ErrAuthFailed,
+		},
+		{
+			name:      "missing repository isn't retried",
+			attempts:  3,
+			failures:  1,
+			stderr:    "ERROR: Repository not found.\nfatal: the remote end hung up unexpectedly\n",
+			wantCalls: 1,
+			wantErr:   ErrRepoNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastRetries(t, tt.attempts)
+			url := bareRepository(t)
+
+			var calls atomic.Int32
+			exec.CommandRunner = failingRunner(tt.failures, tt.stderr, &calls)
+
+			dir := filepath.Join(t.TempDir(), "clone")
+			err := NewCLI().Clone(&Options{Context: context.Background(), Url: url, Dir: dir})
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Clone() error = %v, want success", err)
+			case tt.wantErr == errAny && err == nil, tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Clone() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("git was run %d times, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr == nil {
+				if output, err := osexec.Command("git", "-C", dir, "log", "--oneline").CombinedOutput(); err != nil {
+					t.Errorf("clone isn't a repository: %v: %s", err, output)
+				}
+			}
+		})
+	}
+}
+
+func TestCloneTimeoutIsRetried(t *testing.T) {
+	fastRetries(t, 3)
+	Timeouts.Clone = 20 * time.Millisecond
+
+	var calls atomic.Int32
+	exec.CommandRunner = func(cmd *osexec.Cmd) error {
+		call := calls.Add(1)
+		if call > 2 {
+			return cmd.Run()
+		}
+
+		// A hung clone, killed by the timeout
+		time.Sleep(2 * Timeouts.Clone)
+		if call == 2 {
+			// Leave the real clone of the last attempt enough time
+			Timeouts.Clone = time.Minute
+		}
+		return errors.New("signal: killed")
+	}
+
+	err := NewCLI().Clone(&Options{Context: context.Background(), Url: bareRepository(t), Dir: filepath.Join(t.TempDir(), "clone")})
+	if err != nil {
+		t.Fatalf("Clone() error = %v, want success after the timeouts", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("git was run %d times, want 3", got)
+	}
+}
+
+func TestCloneTimeoutExhaustsAttempts(t *testing.T) {
+	fastRetries(t, 3)
+	Timeouts.Clone = 20 * time.Millisecond
+
+	var calls atomic.Int32
+	exec.CommandRunner = func(cmd *osexec.Cmd) error {
+		calls.Add(1)
+		time.Sleep(2 * Timeouts.Clone)
+		return errors.New("signal: killed")
+	}
+
+	err := NewCLI().Clone(&Options{Context: context.Background(), Url: "file:///nowhere.git", Dir: filepath.Join(t.TempDir(), "clone")})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Clone() error = %v, want a timeout", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("git was run %d times, want 3", got)
+	}
+}
+
+func TestCloneParentDeadlineIsNotRetried(t *testing.T) {
+	fastRetries(t, 3)
+	Timeouts.Clone = time.Minute
+
+	var calls atomic.Int32
+	exec.CommandRunner = func(cmd *osexec.Cmd) error {
+		calls.Add(1)
+		time.Sleep(40 * time.Millisecond)
+		return errors.New("signal: killed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := NewCLI().Clone(&Options{Context: ctx, Url: "file:///nowhere.git", Dir: filepath.Join(t.TempDir(), "clone")})
+	if err == nil {
+		t.Fatal("Clone() succeeded, want a failure")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("git was run %d times, want 1", got)
+	}
+}
+
+func TestWithRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		minTotal time.Duration // Sum of the backoff delays
+	}{
+		{
+			name:     "exponential",
+			policy:   RetryPolicy{Attempts: 4, InitialDelay: 10 * time.Millisecond},
+			minTotal: (10 + 20 + 40) * time.Millisecond,
+		},
+		{
+			name:     "capped by max delay",
+			policy:   RetryPolicy{Attempts: 4, InitialDelay: 10 * time.Millisecond, MaxDelay: 15 * time.Millisecond},
+			minTotal: (10 + 15 + 15) * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastRetries(t, 0)
+			Retry = tt.policy
+
+			var starts []time.Time
+			transient := &exec.RunError{ExecError: errors.New("exit status 128"), Stderr: "fatal: early EOF"}
+			err := withRetry(&Options{}, OpFetch, func(ctx context.Context) error {
+				starts = append(starts, time.Now())
+				return transient
+			})
+
+			if !errors.Is(err, transient) {
+				t.Fatalf("withRetry() error = %v, want the last failure", err)
+			}
+			if int64(len(starts)) != tt.policy.Attempts {
+				t.Fatalf("%d attempts, want %d", len(starts), tt.policy.Attempts)
+			}
+			if total := starts[len(starts)-1].Sub(starts[0]); total < tt.minTotal {
+				t.Errorf("backoff took %s, want at least %s", total, tt.minTotal)
+			}
+			if tt.policy.MaxDelay > 0 {
+				// Delays never exceed the cap by more than the scheduling noise
+				for i := 1; i < len(starts); i++ {
+					if delay := starts[i].Sub(starts[i-1]); delay > tt.policy.MaxDelay+50*time.Millisecond {
+						t.Errorf("delay %d was %s, above the max delay %s", i, delay, tt.policy.MaxDelay)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsOnCancel(t *testing.T) {
+	fastRetries(t, 5)
+	Retry.InitialDelay = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := withRetry(&Options{Context: ctx}, OpFetch, func(context.Context) error {
+		attempts++
+		cancel()
+		return &exec.RunError{ExecError: errors.New("exit status 128"), Stderr: "fatal: early EOF"}
+	})
+
+	if err == nil || attempts != 1 {
+		t.Fatalf("withRetry() = %v after %d attempts, want a failure after 1 attempt", err, attempts)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	runError := func(stderr string) error {
+		return &exec.RunError{ExecError: errors.New("exit status 128"), ExitCode: 128, Stderr: stderr}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection reset", runError("fatal: unable to access 'https://host/repo/': Connection reset by peer"), true},
+		{"hung up", runError("fatal: the remote end hung up unexpectedly"), true},
+		{"dns", runError("ssh: Could not resolve hostname host: Temporary failure in name resolution"), true},
+		{"http 503", runError("error: RPC failed; HTTP 503 Service Unavailable"), true},
+		{"wrapped", fmt.Errorf("fetch: %w", runError("fatal: early EOF")), true},
+		{"not found before hung up", runError("ERROR: Repository not found.\nfatal: the remote end hung up unexpectedly"), false},
+		{"rejected push", runError("! [rejected] main -> main (fetch first)"), false},
+		{"classified auth failure", Classify(runError("Permission denied (publickey).\nfatal: the remote end hung up unexpectedly")), false},
+		{"unknown failure", runError("fatal: bad object HEAD"), false},
+		{"not a command failure", errors.New("connection reset"), false},
+		{"cancelled", context.Canceled, false},
+		{"own timeout", &timeoutError{op: OpClone, timeout: 30 * time.Minute, err: runError("Receiving objects: 10%")}, true},
+		{"wrapped own timeout", fmt.Errorf("clone: %w", &timeoutError{op: OpClone, timeout: time.Minute, err: errors.New("signal: killed")}), true},
+		{"deadline of the caller", &exec.RunError{ExecError: context.DeadlineExceeded, Stderr: "Receiving objects: 10%"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
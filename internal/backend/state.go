@@ -0,0 +1,171 @@
+package backend
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RepoState is a snapshot of a repository taken with a single "git status --porcelain=v2"
+type RepoState struct {
+	Branch    string // Checked out branch, empty when detached
+	Detached  bool
+	Head      string // Commit HEAD points to, empty for repositories without commits
+	Upstream  string // Upstream of the branch (e.g. origin/main), empty when not configured
+	Ahead     int    // Commits missing on the upstream
+	Behind    int    // Commits of the upstream missing locally
+	Staged    int    // Files with changes in the index
+	Unstaged  int    // Tracked files with changes in the worktree
+	Untracked int
+	Conflicts int // Unmerged files
+	Stashes   int
+}
+
+// BranchState is a local branch compared with its upstream
+type BranchState struct {
+	Name     string
+	Upstream string // Upstream of the branch, empty when not configured
+	Gone     bool   // The upstream was deleted on the remote
+	Ahead    int    // Commits missing on the upstream
+}
+
+// Unpushed reports whether the commits of the branch may be missing on the remote: it's ahead of its
+// upstream, or has no upstream to compare with
+func (b *BranchState) Unpushed() bool {
+	return b.Upstream == "" || b.Gone || b.Ahead > 0
+}
+
+// ParseBranches parses the output of "git for-each-ref" with the branchesFormat format
+func ParseBranches(output string) ([]BranchState, error) {
+	var branches []BranchState
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected branch line %q", line)
+		}
+
+		branch := BranchState{Name: fields[0], Upstream: fields[1]}
+		// "[ahead 2, behind 1]", "[behind 1]", "[gone]" or empty when up to date
+		track := strings.Trim(fields[2], "[]")
+		for _, part := range strings.Split(track, ", ") {
+			switch {
+			case part == "gone":
+				branch.Gone = true
+			case strings.HasPrefix(part, "ahead "):
+				ahead, err := strconv.Atoi(strings.TrimPrefix(part, "ahead "))
+				if err != nil {
+					return nil, fmt.Errorf("unexpected branch line %q: %w", line, err)
+				}
+				branch.Ahead = ahead
+			}
+		}
+		branches = append(branches, branch)
+	}
+	return branches, nil
+}
+
+// Dirty reports whether the worktree or the index has any changes
+func (s *RepoState) Dirty() bool {
+	return s.Staged+s.Unstaged+s.Untracked+s.Conflicts > 0
+}
+
+// Modified reports whether tracked files have uncommitted changes, unlike Dirty untracked files don't count
+func (s *RepoState) Modified() bool {
+	return s.Staged+s.Unstaged+s.Conflicts > 0
+}
+
+// Unpushed reports whether the branch has commits missing on its upstream
+func (s *RepoState) Unpushed() bool {
+	return s.Ahead > 0
+}
+
+// Empty reports whether the repository has no commits
+func (s *RepoState) Empty() bool {
+	return s.Head == ""
+}
+
+// ParseStatusV2 parses the output of "git status --porcelain=v2 --branch --show-stash"
+func ParseStatusV2(output string) (*RepoState, error) {
+	state := &RepoState{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			if err := state.parseHeader(header); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		kind, rest, _ := strings.Cut(line, " ")
+		switch kind {
+		case "1", "2":
+			// <XY> <sub> ... <path>
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("unexpected status line %q", line)
+			}
+			if rest[0] != '.' {
+				state.Staged++
+			}
+			if rest[1] != '.' {
+				state.Unstaged++
+			}
+		case "u":
+			state.Conflicts++
+		case "?":
+			state.Untracked++
+		case "!":
+			// Ignored files are not part of the state
+		default:
+			return nil, fmt.Errorf("unexpected status line %q", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// parseHeader parses a "# <key> <value>" header line of porcelain v2
+func (s *RepoState) parseHeader(header string) error {
+	key, value, _ := strings.Cut(header, " ")
+
+	switch key {
+	case "branch.oid":
+		if value != "(initial)" {
+			s.Head = value
+		}
+	case "branch.head":
+		if value == "(detached)" {
+			s.Detached = true
+		} else {
+			s.Branch = value
+		}
+	case "branch.upstream":
+		s.Upstream = value
+	case "branch.ab":
+		_, err := fmt.Sscanf(value, "+%d -%d", &s.Ahead, &s.Behind)
+		if err != nil {
+			return fmt.Errorf("unexpected branch.ab header %q: %w", value, err)
+		}
+	case "stash":
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("unexpected stash header %q: %w", value, err)
+		}
+		s.Stashes = count
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseStatusV2(t *testing.T) {
+	const head = "1f2e3d4c5b6a79887766554433221100ffeeddcc"
+
+	tests := []struct {
+		name    string
+		output  string
+		want    RepoState
+		wantErr bool
+	}{
+		{
+			name:   "clean",
+			output: "# branch.oid " + head + "\n# branch.head main\n# branch.upstream origin/main\n# branch.ab +0 -0\n",
+			want:   RepoState{Branch: "main", Head: head, Upstream: "origin/main"},
+		},
+		{
+			name: "ahead and behind with changes",
+			output: "# branch.oid " + head + "\n" +
+				"# branch.head feature/login\n" +
+				"# branch.upstream origin/feature/login\n" +
+				"# branch.ab +2 -3\n" +
+				"# stash 4\n" +
+				"1 M. N... 100644 100644 100644 aaaa bbbb staged.go\n" +
+				"1 .M N... 100644 100644 100644 aaaa aaaa unstaged.go\n" +
+				"1 MM N... 100644 100644 100644 aaaa bbbb both.go\n" +
+				"2 R. N... 100644 100644 100644 aaaa aaaa R100 new.go\told.go\n" +
+				"u UU N... 100644 100644 100644 100644 aaaa bbbb cccc conflict.go\n" +
+				"? untracked.go\n" +
+				"? other file.txt\n" +
+				"! ignored.log\n",
+			want: RepoState{
+				Branch:    "feature/login",
+				Head:      head,
+				Upstream:  "origin/feature/login",
+				Ahead:     2,
+				Behind:    3,
+				Staged:    3,
+				Unstaged:  2,
+				Untracked: 2,
+				Conflicts: 1,
+				Stashes:   4,
+			},
+		},
+		{
+			name:   "no upstream",
+			output: "# branch.oid " + head + "\n# branch.head local\n? new.go\n",
+			want:   RepoState{Branch: "local", Head: head, Untracked: 1},
+		},
+		{
+			name:   "detached",
+			output: "# branch.oid " + head + "\n# branch.head (detached)\n",
+			want:   RepoState{Detached: true, Head: head},
+		},
+		{
+			name:   "no commits",
+			output: "# branch.oid (initial)\n# branch.head main\n? README.md\n",
+			want:   RepoState{Branch: "main", Untracked: 1},
+		},
+		{
+			name:   "unknown headers are ignored",
+			output: "# branch.oid " + head + "\n# branch.head main\n# future.header value\n\n",
+			want:   RepoState{Branch: "main", Head: head},
+		},
+		{
+			name:    "invalid ahead behind",
+			output:  "# branch.ab ahead\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid stash",
+			output:  "# stash many\n",
+			wantErr: true,
+		},
+		{
+			name:    "short status line",
+			output:  "1 M\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown status line",
+			output:  "X something\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatusV2(tt.output)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseStatusV2() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStatusV2() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseStatusV2() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBranches(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []BranchState
+		wantErr bool
+	}{
+		{
+			name:   "no branches",
+			output: "",
+		},
+		{
+			name: "tracking states",
+			output: "main\x00origin/main\x00\n" +
+				"feature/login\x00origin/feature/login\x00[ahead 2, behind 1]\n" +
+				"old\x00origin/old\x00[behind 3]\n" +
+				"merged\x00origin/merged\x00[gone]\n" +
+				"local\x00\x00\n",
+			want: []BranchState{
+				{Name: "main", Upstream: "origin/main"},
+				{Name: "feature/login", Upstream: "origin/feature/login", Ahead: 2},
+				{Name: "old", Upstream: "origin/old"},
+				{Name: "merged", Upstream: "origin/merged", Gone: true},
+				{Name: "local"},
+			},
+		},
+		{
+			name:    "missing fields",
+			output:  "main\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid ahead",
+			output:  "main\x00origin/main\x00[ahead many]\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBranches(tt.output)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseBranches() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBranches() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseBranches() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
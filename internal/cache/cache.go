@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"aww/internal/repository"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Path is the folder of the bare mirrors whose objects are shared by clones of the same repository,
+// set from the flags or the configuration by cmd.Configure, DefaultPath otherwise
+var Path string
+
+// DefaultPath returns the cache folder in the configuration folder
+func DefaultPath() string {
+	return filepath.Join(repository.RepositoryPath, "cache")
+}
+
+// Dir returns the mirror of a repository. Urls of the same repository (ssh or https, with or without
+// the user, the port or ".git") share a mirror, laid out like the clones: <host>/<path>.git
+func Dir(url string) (string, error) {
+	remote, err := repository.ParseURL(url)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(Path, remote.FQDN(), filepath.FromSlash(remote.Path)+".git"), nil
+}
+
+// Mirrors returns the mirrors of the cache, a missing cache has none
+func Mirrors() ([]string, error) {
+	var mirrors []string
+	err := filepath.WalkDir(Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() || path == Path {
+			return nil
+		}
+		// Mirrors being created
+		if strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if strings.HasSuffix(entry.Name(), ".git") && isBare(path) {
+			mirrors = append(mirrors, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading cache directory: %w", err)
+	}
+	return mirrors, nil
+}
+
+// isBare reports whether the folder is a bare repository
+func isBare(path string) bool {
+	head, err := os.Stat(filepath.Join(path, "HEAD"))
+	if err != nil || head.IsDir() {
+		return false
+	}
+	objects, err := os.Stat(filepath.Join(path, "objects"))
+	return err == nil && objects.IsDir()
+}
+
+// Remove deletes a mirror and the folders of the cache it leaves empty
+func Remove(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error removing mirror %s: %w", dir, err)
+	}
+
+	for parent := filepath.Dir(dir); parent != Path && strings.HasPrefix(parent, Path); parent = filepath.Dir(parent) {
+		// Fails for folders holding other mirrors
+		if os.Remove(parent) != nil {
+			break
+		}
+	}
+	return nil
+}
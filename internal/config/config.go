@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"aww/internal/repository"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FilePath is the path to the global configuration file, set by the --config flag or DefaultFilePath
+var FilePath string
+
+// DefaultFilePath returns the configuration file in the configuration folder
+func DefaultFilePath() string {
+	return filepath.Join(repository.RepositoryPath, "config.yaml")
+}
+
+// Output formats of log messages
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Timeouts holds the timeouts of git operations, nil keeps the built-in value
+type Timeouts struct {
+	Clone *time.Duration `yaml:"clone,omitempty"`
+	Fetch *time.Duration `yaml:"fetch,omitempty"`
+	Pull  *time.Duration `yaml:"pull,omitempty"`
+	Push  *time.Duration `yaml:"push,omitempty"`
+	Local *time.Duration `yaml:"local,omitempty"`
+}
+
+// Settings are the values a configuration or a profile can set, empty values keep the defaults
+type Settings struct {
+	Root             string   `yaml:"root,omitempty"`              // Root directory of cloned repositories
+	Layout           string   `yaml:"layout,omitempty"`            // Layout template of paths below the root
+	RepositoriesFile string   `yaml:"repositories_file,omitempty"` // Repositories file
+	Jobs             int64    `yaml:"jobs,omitempty"`              // Number of repositories processed concurrently
+	Remote           string   `yaml:"remote,omitempty"`            // Remote used by fetch, pull and push
+	Backend          string   `yaml:"backend,omitempty"`           // Git backend (cli, go-git)
+	Output           string   `yaml:"output,omitempty"`            // Log output format (text, json)
+	Retries          *int64   `yaml:"retries,omitempty"`           // Attempts of network operations
+	Cache            *bool    `yaml:"cache,omitempty"`             // Clone through the shared object cache
+	Dissociate       *bool    `yaml:"dissociate,omitempty"`        // Copy the objects borrowed from the cache
+	CacheDir         string   `yaml:"cache_dir,omitempty"`         // Folder of the mirrors of the cache
+	Timeouts         Timeouts `yaml:"timeouts,omitempty"`
+}
+
+// Config is the content of the global configuration file
+type Config struct {
+	Settings `yaml:",inline"`
+
+	// Profile used when neither --profile nor AWW_PROFILE is set
+	DefaultProfile string              `yaml:"default_profile,omitempty"`
+	Profiles       map[string]Settings `yaml:"profiles,omitempty"`
+}
+
+// Load reads the configuration file, a missing file is an empty configuration
+func Load(path string) (*Config, error) {
+	config := &Config{}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// Resolve returns the settings of the profile merged over the top-level settings.
+// An empty name selects the default profile, if any.
+func (c *Config) Resolve(name string) (*Settings, error) {
+	settings := c.Settings
+
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name != "" {
+		profile, ok := c.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("profile '%s' not found in %s (available: %s)", name, FilePath, strings.Join(c.ProfileNames(), ", "))
+		}
+		settings.merge(&profile)
+	}
+
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// ProfileNames returns the sorted names of all profiles
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// merge overrides the settings with the values set in other
+func (s *Settings) merge(other *Settings) {
+	mergeString(&s.Root, other.Root)
+	mergeString(&s.Layout, other.Layout)
+	mergeString(&s.RepositoriesFile, other.RepositoriesFile)
+	mergeString(&s.Remote, other.Remote)
+	mergeString(&s.Backend, other.Backend)
+	mergeString(&s.Output, other.Output)
+	mergeString(&s.CacheDir, other.CacheDir)
+	if other.Jobs != 0 {
+		s.Jobs = other.Jobs
+	}
+	if other.Retries != nil {
+		s.Retries = other.Retries
+	}
+	if other.Cache != nil {
+		s.Cache = other.Cache
+	}
+	if other.Dissociate != nil {
+		s.Dissociate = other.Dissociate
+	}
+	mergeDuration(&s.Timeouts.Clone, other.Timeouts.Clone)
+	mergeDuration(&s.Timeouts.Fetch, other.Timeouts.Fetch)
+	mergeDuration(&s.Timeouts.Pull, other.Timeouts.Pull)
+	mergeDuration(&s.Timeouts.Push, other.Timeouts.Push)
+	mergeDuration(&s.Timeouts.Local, other.Timeouts.Local)
+}
+
+func (s *Settings) validate() error {
+	if s.Output != "" && s.Output != OutputText && s.Output != OutputJSON {
+		return fmt.Errorf("invalid output '%s' in %s (available: %s, %s)", s.Output, FilePath, OutputText, OutputJSON)
+	}
+	if s.Jobs < 0 {
+		return fmt.Errorf("invalid jobs %d in %s", s.Jobs, FilePath)
+	}
+	return nil
+}
+
+func mergeString(target *string, value string) {
+	if value != "" {
+		*target = value
+	}
+}
+
+func mergeDuration(target **time.Duration, value *time.Duration) {
+	if value != nil {
+		*target = value
+	}
+}
@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultJobs is the number of workers used when a caller asks for less than one
+const DefaultJobs = 4
+
+// Run executes task for every index in [0, n) using at most jobs concurrent workers.
+// The returned slice has one entry per task, so errors keep the order of the input
+// regardless of the order in which the tasks finished.
+// Once ctx is cancelled no new tasks are started and their entries hold ctx.Err().
+func Run(ctx context.Context, jobs, n int, task func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	if n == 0 {
+		return errs
+	}
+
+	if jobs < 1 {
+		jobs = DefaultJobs
+	}
+	if jobs > n {
+		jobs = n
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = task(ctx, i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+			// Workers are busy or gone, mark the rest as cancelled
+			for ; i < n; i++ {
+				errs[i] = ctx.Err()
+			}
+		}
+	}
+	close(queue)
+
+	wg.Wait()
+
+	return errs
+}
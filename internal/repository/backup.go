@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// BackupPath is the folder keeping previous versions of the repositories files
+	BackupPath = filepath.Join(RepositoryPath, "backups")
+	// MaxBackups is the number of backups kept for every file, older ones are removed
+	MaxBackups = 10
+)
+
+// backupTimeFormat is sortable, so the names of the backups of a file sort by age
+const backupTimeFormat = "20060102-150405.000000000"
+
+// Backup is a previous version of a repositories file
+type Backup struct {
+	Name string    // Name of the backup file
+	Path string    // Path of the backup file
+	Time time.Time // Time the backup was taken
+}
+
+// backupFolder returns the folder of the backups of a file
+func backupFolder(path string) (string, error) {
+	key, err := fileKey(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(BackupPath, key), nil
+}
+
+// fileKey names the data kept for a file, files of the same name in different folders get their own
+func fileKey(path string) (string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("error resolving path %s: %w", path, err)
+	}
+	sum := sha256.Sum256([]byte(absolute))
+	return filepath.Base(absolute) + "-" + hex.EncodeToString(sum[:4]), nil
+}
+
+// backup keeps the content of the file before it's overwritten and removes the oldest backups
+func backup(path string, content []byte) error {
+	folder, err := backupFolder(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return fmt.Errorf("error creating backup directory: %w", err)
+	}
+
+	name := filepath.Base(path) + "." + time.Now().Format(backupTimeFormat) + ".bak"
+	if err := writeFile(filepath.Join(folder, name), content, 0644); err != nil {
+		return fmt.Errorf("error writing backup of %s: %w", path, err)
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		return err
+	}
+	for _, old := range backups[min(len(backups), max(MaxBackups, 1)):] {
+		if err := os.Remove(old.Path); err != nil {
+			return fmt.Errorf("error removing backup %s: %w", old.Path, err)
+		}
+	}
+	return nil
+}
+
+// Backups returns the backups of the file, newest first
+func Backups(path string) ([]Backup, error) {
+	folder, err := backupFolder(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(folder)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading backup directory: %w", err)
+	}
+
+	prefix := filepath.Base(path) + "."
+	var backups []Backup
+	for _, file := range files {
+		stamp, ok := strings.CutPrefix(file.Name(), prefix)
+		if !ok || file.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, ".bak")
+		if !ok {
+			continue
+		}
+		taken, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Name: file.Name(), Path: filepath.Join(folder, file.Name()), Time: taken})
+	}
+
+	slices.SortFunc(backups, func(a, b Backup) int { return b.Time.Compare(a.Time) })
+	return backups, nil
+}
+
+// Restore replaces the file with one of its backups, the replaced content is backed up too
+func Restore(path string, from Backup) error {
+	content, err := os.ReadFile(from.Path)
+	if err != nil {
+		return fmt.Errorf("error reading backup: %w", err)
+	}
+
+	return replaceFile(path, nil, content)
+}
+
+// writeFile replaces the file atomically: the content is written to a temporary file of the
+// same folder which is then renamed over it, so a crash never leaves a partially written file
+func writeFile(path string, content []byte, mode os.FileMode) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing fails once the file is renamed
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+// fileMode returns the permissions of an existing file, new files are readable by everyone
+func fileMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
+}
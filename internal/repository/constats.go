@@ -8,13 +8,65 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// Define pattern for extracting components from the SSH URL
-var pattern = `^git@([a-zA-Z0-9.-]+):([a-zA-Z0-9_./-]+)\.git$`
+// Filters of partial clones supported by git
+var filterPattern = regexp.MustCompile(`^(blob:none|blob:limit=\d+[kmgKMG]?|tree:\d+|object:type=(blob|tree|commit|tag)|sparse:oid=\S+|combine:\S+)$`)
 
 type Group struct {
 	Name     string        `yaml:"name"`
+	Labels   []string      `yaml:"labels,omitempty"`
+	Root     string        `yaml:"root,omitempty"`   // Root directory of the group, the global root when empty
+	Layout   string        `yaml:"layout,omitempty"` // Layout template of the group, the global layout when empty
 	Actions  *GroupActions `yaml:"actions,omitempty"`
+	Clone    *CloneOptions `yaml:"clone,omitempty"` // Clone options of the projects of the group
 	Projects []*Project    `yaml:"projects,omitempty"`
+
+	file string // Repositories file the group was loaded from
+}
+
+// File returns the repositories file the group was loaded from
+func (g *Group) File() string {
+	return g.file
+}
+
+// AddProject appends the project to the group
+func (g *Group) AddProject(project *Project) {
+	g.Projects = append(g.Projects, project)
+	project.group = g
+}
+
+// RemoveProject removes the project from the group, false when the group doesn't hold it
+func (g *Group) RemoveProject(project *Project) bool {
+	for i, p := range g.Projects {
+		if p == project {
+			g.Projects = append(g.Projects[:i], g.Projects[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// FindGroup returns the group of the given name, nil when there is none
+func FindGroup(groups []*Group, name string) *Group {
+	for _, group := range groups {
+		if group.Name == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// FindProject returns the project of the repository behind the url and its group, any url form
+// of the repository matches
+func FindProject(groups []*Group, url string) (*Group, *Project) {
+	key := projectKey(url)
+	for _, group := range groups {
+		for _, project := range group.Projects {
+			if projectKey(project.Url) == key {
+				return group, project
+			}
+		}
+	}
+	return nil, nil
 }
 
 type GroupActions struct {
@@ -31,11 +83,55 @@ func (g *GroupActions) Reset() {
 
 type Project struct {
 	Url     string          `yaml:"url"`
+	Path    string          `yaml:"path,omitempty"` // Explicit path, absolute or relative to the root, overrides the layout
+	Labels  []string        `yaml:"labels,omitempty"`
 	Actions *ProjectActions `yaml:"actions,omitempty"`
+	Clone   *CloneOptions   `yaml:"clone,omitempty"` // Clone options, override the options of the group
 
 	FQDN    string `yaml:"-"`
 	Folders string `yaml:"-"`
-	Path    string `yaml:"-"`
+	Port    string `yaml:"-"` // Custom port of the remote, empty when default
+
+	group *Group // Group of the project, set on load
+}
+
+// CloneOptions control how a repository is cloned. Unset (nil) options are inherited from the group,
+// empty values keep the defaults of git, so a project can get back a full clone with depth: 0.
+type CloneOptions struct {
+	Depth        *int     `yaml:"depth,omitempty"`         // Shallow clone of the last commits, the full history when 0
+	Filter       *string  `yaml:"filter,omitempty"`        // Partial clone filter (e.g. blob:none), none when empty
+	Sparse       []string `yaml:"sparse,omitempty"`        // Sparse checkout patterns, the full worktree when empty
+	Branch       *string  `yaml:"branch,omitempty"`        // Branch checked out instead of the default one
+	SingleBranch *bool    `yaml:"single_branch,omitempty"` // Only fetch the checked out branch
+}
+
+// GetDepth returns the depth of a shallow clone, 0 for the full history
+func (c *CloneOptions) GetDepth() int {
+	if c.Depth == nil {
+		return 0
+	}
+	return *c.Depth
+}
+
+// GetFilter returns the partial clone filter, empty for a full clone
+func (c *CloneOptions) GetFilter() string {
+	if c.Filter == nil {
+		return ""
+	}
+	return *c.Filter
+}
+
+// GetBranch returns the branch checked out after cloning, empty for the default branch
+func (c *CloneOptions) GetBranch() string {
+	if c.Branch == nil {
+		return ""
+	}
+	return *c.Branch
+}
+
+// GetSingleBranch reports whether only the checked out branch is fetched
+func (c *CloneOptions) GetSingleBranch() bool {
+	return c.SingleBranch != nil && *c.SingleBranch
 }
 
 type ProjectActions struct {
@@ -58,40 +154,95 @@ func (p *Project) GetFolders() string {
 	return p.Folders
 }
 
-func (p *Project) GetPath() string {
-	return filepath.Join(DestRepoPath, p.FQDN, p.Folders)
+// GetLabels returns the labels of the project together with the labels inherited from its group
+func (p *Project) GetLabels(group *Group) []string {
+	var labels []string
+	seen := map[string]bool{}
+
+	if group != nil {
+		for _, label := range group.Labels {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	for _, label := range p.Labels {
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+
+	return labels
 }
 
-func (p *Project) Validate(url string) error {
-	matched, err := regexp.MatchString(pattern, url)
-	if err != nil {
-		return fmt.Errorf("error validating SSH URL: %v", err)
+// Check reports invalid clone options
+func (c *CloneOptions) Check() error {
+	if depth := c.GetDepth(); depth < 0 {
+		return fmt.Errorf("clone depth must be positive, got %d", depth)
 	}
-	if !matched {
-		return fmt.Errorf("invalid SSH URL provided (example: git@gitlab.com:goodgroup/goodrepo.git)")
+	if filter := c.GetFilter(); filter != "" && !filterPattern.MatchString(filter) {
+		return fmt.Errorf("invalid clone filter '%s' (e.g. blob:none, blob:limit=1m, tree:0)", filter)
 	}
-
 	return nil
 }
 
-// Decode parses an SSH URL into a Project struct
-func (p *Project) Decode() error {
-	// Validate the SSH URL
-	err := p.Validate(p.Url)
+// GetCloneOptions returns the clone options of the project merged over the options of its group,
+// options set by the project win even when empty
+func (p *Project) GetCloneOptions() CloneOptions {
+	var options CloneOptions
+	if p.group != nil && p.group.Clone != nil {
+		options = *p.group.Clone
+	}
+	if p.Clone == nil {
+		return options
+	}
+
+	if p.Clone.Depth != nil {
+		options.Depth = p.Clone.Depth
+	}
+	if p.Clone.Filter != nil {
+		options.Filter = p.Clone.Filter
+	}
+	if p.Clone.Sparse != nil {
+		options.Sparse = p.Clone.Sparse
+	}
+	if p.Clone.Branch != nil {
+		options.Branch = p.Clone.Branch
+	}
+	if p.Clone.SingleBranch != nil {
+		options.SingleBranch = p.Clone.SingleBranch
+	}
+	return options
+}
+
+// GetPath returns the path of the project on disk. Paths of loaded projects were checked by Load,
+// the default layout is only used for projects built in code with an unusable path.
+func (p *Project) GetPath() string {
+	projectPath, err := p.ResolvePath()
 	if err != nil {
-		return err
+		log.Error().Err(err).Str("url", p.Url).Msg("Failed to resolve project path, using the default layout")
+		return filepath.Join(p.GetRoot(), p.FQDN, p.Folders)
 	}
+	return projectPath
+}
 
-	regex := regexp.MustCompile(pattern)
-	submatches := regex.FindStringSubmatch(p.Url)
+func (p *Project) Validate(url string) error {
+	_, err := ParseURL(url)
+	return err
+}
 
-	// Ensure the submatches contain the expected groups
-	if len(submatches) < 2 {
-		return fmt.Errorf("failed to extract groups from SSH URL")
+// Decode parses the git URL (scp-like, ssh://, https://, git:// or file://) into the Project struct
+func (p *Project) Decode() error {
+	remote, err := ParseURL(p.Url)
+	if err != nil {
+		return err
 	}
 
-	p.FQDN = submatches[1]
-	p.Folders = submatches[2]
+	p.FQDN = remote.FQDN()
+	p.Folders = remote.Folders()
+	p.Port = remote.Port
 	log.Debug().Str("service", "helpers").Str("folders", p.Folders).Str("fqdn", p.FQDN).Send()
 	return nil
 }
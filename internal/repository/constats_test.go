@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGetCloneOptions(t *testing.T) {
+	const group = "depth: 1\nfilter: blob:none\nsparse: [docs]\nbranch: develop\nsingle_branch: true\n"
+
+	tests := []struct {
+		name             string
+		group            string // Clone options of the group, none when empty
+		project          string // Clone options of the project, none when empty
+		wantDepth        int
+		wantFilter       string
+		wantSparse       []string
+		wantBranch       string
+		wantSingleBranch bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:             "group",
+			group:            group,
+			wantDepth:        1,
+			wantFilter:       "blob:none",
+			wantSparse:       []string{"docs"},
+			wantBranch:       "develop",
+			wantSingleBranch: true,
+		},
+		{
+			name:       "project",
+			project:    "depth: 5\nsparse: [src, '*.md']\n",
+			wantDepth:  5,
+			wantSparse: []string{"src", "*.md"},
+		},
+		{
+			name:             "project overrides some fields",
+			group:            group,
+			project:          "depth: 10\nbranch: main\n",
+			wantDepth:        10,
+			wantFilter:       "blob:none",
+			wantSparse:       []string{"docs"},
+			wantBranch:       "main",
+			wantSingleBranch: true,
+		},
+		{
+			name:    "project restores the defaults",
+			group:   group,
+			project: "depth: 0\nfilter: \"\"\nsparse: []\nbranch: \"\"\nsingle_branch: false\n",
+		},
+		{
+			name:             "empty project options",
+			group:            group,
+			project:          "{}",
+			wantDepth:        1,
+			wantFilter:       "blob:none",
+			wantSparse:       []string{"docs"},
+			wantBranch:       "develop",
+			wantSingleBranch: true,
+		},
+	}
+
+	decode := func(t *testing.T, text string) *CloneOptions {
+		t.Helper()
+		if text == "" {
+			return nil
+		}
+		options := &CloneOptions{}
+		if err := yaml.Unmarshal([]byte(text), options); err != nil {
+			t.Fatal(err)
+		}
+		return options
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Group{Name: "group", Clone: decode(t, tt.group)}
+			project := &Project{Url: "git@host:group/repo.git", Clone: decode(t, tt.project)}
+			g.AddProject(project)
+
+			options := project.GetCloneOptions()
+			if got := options.GetDepth(); got != tt.wantDepth {
+				t.Errorf("depth = %d, want %d", got, tt.wantDepth)
+			}
+			if got := options.GetFilter(); got != tt.wantFilter {
+				t.Errorf("filter = %q, want %q", got, tt.wantFilter)
+			}
+			if !slices.Equal(options.Sparse, tt.wantSparse) {
+				t.Errorf("sparse = %q, want %q", options.Sparse, tt.wantSparse)
+			}
+			if got := options.GetBranch(); got != tt.wantBranch {
+				t.Errorf("branch = %q, want %q", got, tt.wantBranch)
+			}
+			if got := options.GetSingleBranch(); got != tt.wantSingleBranch {
+				t.Errorf("single branch = %v, want %v", got, tt.wantSingleBranch)
+			}
+		})
+	}
+}
+
+func TestCloneOptionsCheck(t *testing.T) {
+	tests := []struct {
+		options string
+		wantErr string
+	}{
+		{"{}", ""},
+		{"depth: 0", ""},
+		{"depth: 3", ""},
+		{"depth: -1", "clone depth must be positive"},
+		{"filter: \"\"", ""},
+		{"filter: blob:none", ""},
+		{"filter: blob:limit=1m", ""},
+		{"filter: tree:0", ""},
+		{"filter: blobs", "invalid clone filter 'blobs'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.options, func(t *testing.T) {
+			options := &CloneOptions{}
+			if err := yaml.Unmarshal([]byte(tt.options), options); err != nil {
+				t.Fatal(err)
+			}
+
+			err := options.Check()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Check() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCloneOptionsRoundTrip(t *testing.T) {
+	// Explicit defaults are written back, they override the group
+	options := &CloneOptions{}
+	if err := yaml.Unmarshal([]byte("depth: 0\nfilter: \"\"\nbranch: \"\"\n"), options); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := render(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "depth: 0\nfilter: \"\"\nbranch: \"\"\n"; text != want {
+		t.Errorf("rendered %q, want %q", text, want)
+	}
+}
@@ -0,0 +1,439 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// errRewrite reports a change which can't be applied in place, the file has to be encoded again
+var errRewrite = errors.New("change can't be applied in place")
+
+// edit replaces content[start:end] of a document with text
+type edit struct {
+	start int
+	end   int
+	text  string
+}
+
+// document applies minimal edits to the text of a repositories file, every byte outside
+// of the changed groups, projects and fields (comments, blank lines, formatting) is kept
+type document struct {
+	content []byte
+	lines   []int // Offset of the start of every line, followed by the length of the content
+	edits   []edit
+}
+
+func newDocument(content []byte) *document {
+	d := &document{content: content, lines: []int{0}}
+	for i, b := range content {
+		if b == '\n' && i+1 < len(content) {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	d.lines = append(d.lines, len(content))
+	return d
+}
+
+// lineCount returns the number of lines of the document
+func (d *document) lineCount() int {
+	return len(d.lines) - 1
+}
+
+// line returns the text of the line without the line break
+func (d *document) line(i int) string {
+	return strings.TrimRight(string(d.content[d.lines[i]:d.lines[i+1]]), "\r\n")
+}
+
+// indent returns the number of leading spaces of the line
+func (d *document) indent(i int) int {
+	line := d.line(i)
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// isContent reports whether the line holds yaml content, not only blanks or a comment
+func (d *document) isContent(i int) bool {
+	trimmed := strings.TrimSpace(d.line(i))
+	return trimmed != "" && !strings.HasPrefix(trimmed, "#")
+}
+
+// blockEnd returns the line after the last content line of the block starting at the given line.
+// The block holds the following lines indented deeper than indent, with dash also the sequence
+// items at indent (sequences may be written at the indentation of their key). Trailing blank and
+// comment lines are left outside of the block.
+func (d *document) blockEnd(start, indent int, dash bool) int {
+	last := start
+	for i := start + 1; i < d.lineCount(); i++ {
+		if !d.isContent(i) {
+			continue
+		}
+		lineIndent := d.indent(i)
+		trimmed := strings.TrimSpace(d.line(i))
+		isItem := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+		if lineIndent < indent || (lineIndent == indent && !(dash && isItem)) {
+			break
+		}
+		last = i
+	}
+	return last + 1
+}
+
+// offset returns the offset of a 1-based line and column of a node
+func (d *document) offset(line, column int) int {
+	return d.lines[line-1] + column - 1
+}
+
+// item returns the lines [start, end) of a block sequence item holding the node and the
+// indentation of its dash
+func (d *document) item(node *yaml.Node) (start, indent, end int) {
+	start = node.Line - 1
+	for start > 0 && !strings.HasPrefix(strings.TrimSpace(d.line(start)), "-") {
+		start--
+	}
+	indent = d.indent(start)
+	return start, indent, d.blockEnd(start, indent, false)
+}
+
+// field returns the byte range of a mapping field, from its key to the end of its value, and
+// whether the key starts its line (it doesn't when it's the first key of a sequence item)
+func (d *document) field(key, value *yaml.Node) (start, end int, ownLine bool) {
+	line := key.Line - 1
+	indent := key.Column - 1
+	end = d.lines[d.blockEnd(line, indent, value.Kind == yaml.SequenceNode)]
+
+	if d.indent(line) == indent {
+		return d.lines[line], end, true
+	}
+	return d.offset(key.Line, key.Column), end, false
+}
+
+// scalar returns the byte range of a scalar value written on the line of its key, ok is false for
+// empty values, values spanning several lines and values carrying an anchor or a tag
+func (d *document) scalar(key, value *yaml.Node) (start, end int, ok bool) {
+	if value.Kind != yaml.ScalarNode || value.Line != key.Line || value.Anchor != "" ||
+		value.Style&(yaml.TaggedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 || (value.Style == 0 && value.Value == "") {
+		return 0, 0, false
+	}
+	line := key.Line - 1
+	if d.blockEnd(line, key.Column-1, false) != line+1 {
+		// Plain or quoted value continued on the next lines
+		return 0, 0, false
+	}
+
+	start = d.offset(value.Line, value.Column)
+	text := d.line(line)[start-d.lines[line]:]
+	switch {
+	case value.Style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(text); i++ {
+			if text[i] == '\\' {
+				i++
+			} else if text[i] == '"' {
+				return start, start + i + 1, true
+			}
+		}
+		return 0, 0, false
+	case value.Style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(text); i++ {
+			if text[i] == '\'' {
+				if i+1 < len(text) && text[i+1] == '\'' {
+					i++
+					continue
+				}
+				return start, start + i + 1, true
+			}
+		}
+		return 0, 0, false
+	default:
+		// A comment starts with '#' after a blank
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = text[:i]
+		}
+		return start, start + len(strings.TrimRight(text, " \t")), true
+	}
+}
+
+func (d *document) replace(start, end int, text string) {
+	d.edits = append(d.edits, edit{start, end, text})
+}
+
+// insertLines inserts complete lines before the given line
+func (d *document) insertLines(line int, text string) {
+	offset := d.lines[line]
+	// The last line may miss its line break
+	if offset > 0 && offset == len(d.content) && d.content[offset-1] != '\n' {
+		text = "\n" + text
+	}
+	d.edits = append(d.edits, edit{offset, offset, text})
+}
+
+// removeItem removes a sequence item
+func (d *document) removeItem(node *yaml.Node) {
+	start, _, end := d.item(node)
+	d.replace(d.lines[start], d.lines[end], "")
+}
+
+// replaceItem renders the value over a whole sequence item
+func (d *document) replaceItem(node *yaml.Node, value any) error {
+	start, indent, end := d.item(node)
+	text, err := renderItem(value, indent)
+	if err != nil {
+		return err
+	}
+	d.replace(d.lines[start], d.lines[end], text)
+	return nil
+}
+
+// appendItems renders the values as sequence items at the given line
+func (d *document) appendItems(line, indent int, values []any) error {
+	var builder strings.Builder
+	for _, value := range values {
+		text, err := renderItem(value, indent)
+		if err != nil {
+			return err
+		}
+		builder.WriteString(text)
+	}
+
+	d.insertLines(line, builder.String())
+	return nil
+}
+
+// updateMapping edits the fields of a mapping item which differ between before and after,
+// the fields listed in skip are left to the caller
+func (d *document) updateMapping(raw *yaml.Node, before, after any, skip ...string) error {
+	beforeNode, err := toNode(before)
+	if err != nil {
+		return err
+	}
+	afterNode, err := toNode(after)
+	if err != nil {
+		return err
+	}
+
+	_, _, end := d.item(raw)
+	return d.updateFields(raw, beforeNode, afterNode, end, skip)
+}
+
+// updateFields edits the fields of a block mapping ending before the given line. Nested block
+// mappings are edited field by field, new fields are inserted at the end of the mapping.
+func (d *document) updateFields(raw, beforeNode, afterNode *yaml.Node, end int, skip []string) error {
+	var keys []string
+	for _, node := range []*yaml.Node{afterNode, beforeNode} {
+		for i := 0; i < len(node.Content); i += 2 {
+			if key := node.Content[i].Value; !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	indent := raw.Column - 1
+	var inserts []string
+
+	for _, key := range keys {
+		if slices.Contains(skip, key) {
+			continue
+		}
+
+		_, beforeValue := lookup(beforeNode, key)
+		_, afterValue := lookup(afterNode, key)
+		same, err := equalNodes(beforeValue, afterValue)
+		if err != nil {
+			return err
+		}
+		if same {
+			continue
+		}
+
+		rawKey, rawValue := lookup(raw, key)
+
+		switch {
+		case afterValue != nil && rawKey != nil && isBlockMapping(rawValue) && keepsField(rawValue, afterValue) &&
+			beforeValue.Kind == yaml.MappingNode && afterValue.Kind == yaml.MappingNode:
+			// Fields of the nested mapping unknown to the structs are kept
+			fieldEnd := d.blockEnd(rawKey.Line-1, rawKey.Column-1, false)
+			if err := d.updateFields(rawValue, beforeValue, afterValue, fieldEnd, nil); err != nil {
+				return err
+			}
+		case afterValue != nil && rawKey != nil:
+			// A scalar written on the line of its key is replaced alone, comments after it are kept
+			if start, end, ok := d.scalar(rawKey, rawValue); ok && afterValue.Kind == yaml.ScalarNode {
+				text, err := renderScalar(rawValue, afterValue)
+				if err != nil {
+					return err
+				}
+				if !strings.Contains(text, "\n") {
+					d.replace(start, end, text)
+					continue
+				}
+			}
+
+			start, end, ownLine := d.field(rawKey, rawValue)
+			text, err := renderField(key, afterValue, indent)
+			if err != nil {
+				return err
+			}
+			if !ownLine {
+				text = text[indent:]
+			}
+			d.replace(start, end, text)
+		case afterValue != nil:
+			text, err := renderField(key, afterValue, indent)
+			if err != nil {
+				return err
+			}
+			inserts = append(inserts, text)
+		case rawKey != nil:
+			start, end, ownLine := d.field(rawKey, rawValue)
+			if !ownLine {
+				// The first key of an item carries the dash, it can't be removed in place
+				return errRewrite
+			}
+			d.replace(start, end, "")
+		}
+	}
+
+	if len(inserts) > 0 {
+		d.insertLines(end, strings.Join(inserts, ""))
+	}
+	return nil
+}
+
+// apply returns the content with all edits applied, edits at the same offset keep their order
+func (d *document) apply() ([]byte, error) {
+	edits := slices.Clone(d.edits)
+	slices.SortStableFunc(edits, func(a, b edit) int { return a.start - b.start })
+
+	var buffer bytes.Buffer
+	position := 0
+	for _, e := range edits {
+		if e.start < position {
+			return nil, fmt.Errorf("overlapping edits at offset %d", e.start)
+		}
+		buffer.Write(d.content[position:e.start])
+		buffer.WriteString(e.text)
+		position = e.end
+	}
+	buffer.Write(d.content[position:])
+
+	return buffer.Bytes(), nil
+}
+
+// lookup returns the key and the value of a mapping field
+func lookup(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// isBlockMapping reports whether the node is a non-empty mapping written in block style
+func isBlockMapping(node *yaml.Node) bool {
+	return node != nil && node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0 && len(node.Content) > 0
+}
+
+// keepsField reports whether a field of the raw mapping is still present in the new value,
+// a mapping losing all of its fields is rendered again instead of being left empty
+func keepsField(raw, value *yaml.Node) bool {
+	for i := 0; i+1 < len(raw.Content); i += 2 {
+		if key, _ := lookup(value, raw.Content[i].Value); key != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func toNode(value any) (*yaml.Node, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, fmt.Errorf("error encoding template content: %w", err)
+	}
+	return node, nil
+}
+
+func equalNodes(a, b *yaml.Node) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+	return equalValues(a, b)
+}
+
+func equalValues(a, b any) (bool, error) {
+	first, err := render(a)
+	if err != nil {
+		return false, err
+	}
+	second, err := render(b)
+	if err != nil {
+		return false, err
+	}
+	return first == second, nil
+}
+
+// render encodes a value the way Save always did, with an indentation of 2
+func render(value any) (string, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("error encoding template content: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("error encoding template content: %w", err)
+	}
+	return buffer.String(), nil
+}
+
+// renderField renders "key: value" with every line indented
+func renderField(key string, value *yaml.Node, indent int) (string, error) {
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: key},
+		value,
+	}}
+	text, err := render(mapping)
+	if err != nil {
+		return "", err
+	}
+	return indentLines(text, indent), nil
+}
+
+// renderScalar renders a scalar replacing raw, strings keep the quotes of raw
+func renderScalar(raw, value *yaml.Node) (string, error) {
+	node := *value
+	if node.Tag == "!!str" && node.Style == 0 {
+		node.Style = raw.Style & (yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle)
+	}
+	text, err := render(&node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(text, "\n"), nil
+}
+
+// renderItem renders "- value" with every line indented
+func renderItem(value any, indent int) (string, error) {
+	text, err := render([]any{value})
+	if err != nil {
+		return "", err
+	}
+	return indentLines(text, indent), nil
+}
+
+func indentLines(text string, indent int) string {
+	prefix := strings.Repeat(" ", indent)
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" && line != "\n" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// firstGroup returns the node of the first group of a version 2 repositories file
+func firstGroup(t *testing.T, content string) *yaml.Node {
+	t.Helper()
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		t.Fatal(err)
+	}
+	_, sequence := lookup(root.Content[0], "groups")
+	if sequence == nil || len(sequence.Content) == 0 {
+		t.Fatal("no groups")
+	}
+	return sequence.Content[0]
+}
+
+func TestDocumentUpdateMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		project bool // The first project is edited instead of the group
+		change  func(group *Group, project *Project)
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "first key of an item keeps its comment",
+			content: "version: 2\ngroups:\n  - name: backend  # main\n    root: ~/src\n",
+			change:  func(g *Group, _ *Project) { g.Name = "be" },
+			want:    "version: 2\ngroups:\n  - name: be  # main\n    root: ~/src\n",
+		},
+		{
+			name:    "own line keeps its comment",
+			content: "version: 2\ngroups:\n  - name: backend\n    root: ~/src # sources\n    layout: flat\n",
+			change:  func(g *Group, _ *Project) { g.Root = "~/code" },
+			want:    "version: 2\ngroups:\n  - name: backend\n    root: ~/code # sources\n    layout: flat\n",
+		},
+		{
+			name:    "double quotes are kept",
+			content: "version: 2\ngroups:\n  - name: \"backend\" # main\n",
+			change:  func(g *Group, _ *Project) { g.Name = "api" },
+			want:    "version: 2\ngroups:\n  - name: \"api\" # main\n",
+		},
+		{
+			name:    "single quotes are kept",
+			content: "version: 2\ngroups:\n  - name: 'it''s # here' # main\n",
+			change:  func(g *Group, _ *Project) { g.Name = "api" },
+			want:    "version: 2\ngroups:\n  - name: 'api' # main\n",
+		},
+		{
+			name:    "hash inside a plain value",
+			content: "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit: fix#12 # message\n      push: true\n",
+			change:  func(g *Group, _ *Project) { g.Actions.Commit = "release" },
+			want:    "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit: release # message\n      push: true\n",
+		},
+		{
+			name:    "value needing quotes",
+			content: "version: 2\ngroups:\n  - name: backend # main\n",
+			change:  func(g *Group, _ *Project) { g.Name = "true" },
+			want:    "version: 2\ngroups:\n  - name: \"true\" # main\n",
+		},
+		{
+			name:    "boolean",
+			content: "version: 2\ngroups:\n  - name: backend\n    actions:\n      skip: false # for now\n",
+			change:  func(g *Group, _ *Project) { g.Actions.Skip = true },
+			want:    "version: 2\ngroups:\n  - name: backend\n    actions:\n      skip: true # for now\n",
+		},
+		{
+			name:    "multi-line value replaces the field",
+			content: "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit: fix # message\n",
+			change:  func(g *Group, _ *Project) { g.Actions.Commit = "fix\nbody" },
+			want:    "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit: |-\n        fix\n        body\n",
+		},
+		{
+			name:    "continued plain value replaces the field",
+			content: "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit: a long\n        message\n      push: true\n",
+			change:  func(g *Group, _ *Project) { g.Actions.Commit = "short" },
+			want:    "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit: short\n      push: true\n",
+		},
+		{
+			name:    "empty value replaces the field",
+			content: "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit:\n      push: true\n",
+			change:  func(g *Group, _ *Project) { g.Actions.Commit = "fix" },
+			want:    "version: 2\ngroups:\n  - name: backend\n    actions:\n      commit: fix\n      push: true\n",
+		},
+		{
+			name:    "new field is added at the end",
+			content: "version: 2\ngroups:\n  - name: backend # main\n\n    # sources\n    root: ~/src\n",
+			change:  func(g *Group, _ *Project) { g.Layout = "{{.Folders}}" },
+			want:    "version: 2\ngroups:\n  - name: backend # main\n\n    # sources\n    root: ~/src\n    layout: '{{.Folders}}'\n",
+		},
+		{
+			name:    "removed field",
+			content: "version: 2\ngroups:\n  - name: backend\n    root: ~/src # sources\n    layout: flat\n",
+			change:  func(g *Group, _ *Project) { g.Root = "" },
+			want:    "version: 2\ngroups:\n  - name: backend\n    layout: flat\n",
+		},
+		{
+			name:    "sequence is replaced",
+			content: "version: 2\ngroups:\n  - name: backend\n    labels: [go] # languages\n",
+			change:  func(g *Group, _ *Project) { g.Labels = append(g.Labels, "api") },
+			want:    "version: 2\ngroups:\n  - name: backend\n    labels:\n      - go\n      - api\n",
+		},
+		{
+			name:    "projects are left to the caller",
+			content: "version: 2\ngroups:\n  - name: backend\n    projects:\n      - url: git@host:a.git\n",
+			change:  func(g *Group, _ *Project) { g.Projects = nil },
+			want:    "version: 2\ngroups:\n  - name: backend\n    projects:\n      - url: git@host:a.git\n",
+		},
+		{
+			name:    "project url keeps its comment",
+			content: "version: 2\ngroups:\n  - name: backend\n    projects:\n      - url: git@host:a.git # old\n        path: a\n",
+			project: true,
+			change:  func(_ *Group, p *Project) { p.Url = "git@host:b.git" },
+			want:    "version: 2\ngroups:\n  - name: backend\n    projects:\n      - url: git@host:b.git # old\n        path: a\n",
+		},
+		{
+			name:    "first key of an item can't be removed",
+			content: "version: 2\ngroups:\n  - root: ~/src\n    name: backend\n",
+			change:  func(g *Group, _ *Project) { g.Root = "" },
+			wantErr: errRewrite,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := firstGroup(t, tt.content)
+			if tt.project {
+				_, projects := lookup(node, "projects")
+				node = projects.Content[0]
+			}
+
+			var before, after any
+			var err error
+			if tt.project {
+				b, a := &Project{}, &Project{}
+				err = errors.Join(node.Decode(b), node.Decode(a))
+				tt.change(nil, a)
+				before, after = b, a
+			} else {
+				b, a := &Group{}, &Group{}
+				err = errors.Join(node.Decode(b), node.Decode(a))
+				tt.change(a, nil)
+				before, after = b, a
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			doc := newDocument([]byte(tt.content))
+			err = doc.updateMapping(node, before, after, "projects")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("updateMapping() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("updateMapping() error = %v", err)
+			}
+
+			got, err := doc.apply()
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentItems(t *testing.T) {
+	const content = "version: 2\ngroups:\n  # backend services\n  - name: backend # main\n    projects:\n      - url: git@host:a.git\n\n  - name: frontend\n"
+
+	tests := []struct {
+		name string
+		edit func(doc *document, group *yaml.Node) error
+		want string
+	}{
+		{
+			name: "remove",
+			edit: func(doc *document, group *yaml.Node) error {
+				doc.removeItem(group)
+				return nil
+			},
+			want: "version: 2\ngroups:\n  # backend services\n\n  - name: frontend\n",
+		},
+		{
+			name: "replace",
+			edit: func(doc *document, group *yaml.Node) error {
+				return doc.replaceItem(group, &Group{Name: "api"})
+			},
+			want: "version: 2\ngroups:\n  # backend services\n  - name: api\n\n  - name: frontend\n",
+		},
+		{
+			name: "append",
+			edit: func(doc *document, group *yaml.Node) error {
+				return doc.appendItems(doc.lineCount(), 2, []any{&Group{Name: "tools", Labels: []string{"cli"}}})
+			},
+			want: content + "  - name: tools\n    labels:\n      - cli\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newDocument([]byte(content))
+			if err := tt.edit(doc, firstGroup(t, content)); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := doc.apply()
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentApplyWithoutFinalNewline(t *testing.T) {
+	doc := newDocument([]byte("a: 1"))
+	doc.insertLines(doc.lineCount(), "b: 2\n")
+
+	got, err := doc.apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a: 1\nb: 2\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDocumentOverlappingEdits(t *testing.T) {
+	doc := newDocument([]byte("a: 1\nb: 2\n"))
+	doc.replace(0, 6, "")
+	doc.replace(3, 4, "3")
+
+	if _, err := doc.apply(); err == nil {
+		t.Error("overlapping edits were applied")
+	}
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultLayout keeps repositories under <root>/<host>/<folders>
+const DefaultLayout = "{{.FQDN}}/{{.Folders}}"
+
+// Layout is the global template of project paths below the root directory
+var Layout = DefaultLayout
+
+// LayoutData holds the variables available in layout templates
+type LayoutData struct {
+	Group     string // Name of the group
+	FQDN      string // Host of the remote
+	Port      string // Custom port of the remote, empty when default
+	Folders   string // Full path of the repository on the remote (e.g. team/backend/api)
+	Namespace string // Folders without the repository name (e.g. team/backend)
+	Repo      string // Name of the repository (e.g. api)
+}
+
+// ParseLayout parses a layout template and checks that it only uses known variables
+func ParseLayout(layout string) (*template.Template, error) {
+	tmpl, err := template.New("layout").Option("missingkey=error").Parse(layout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", layout, err)
+	}
+
+	// Unknown fields are only reported on execution
+	sample := LayoutData{Group: "group", FQDN: "host", Folders: "namespace/repo", Namespace: "namespace", Repo: "repo"}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", layout, err)
+	}
+
+	return tmpl, nil
+}
+
+// layouts caches the parsed layouts by their text, a layout is rendered for every project
+var layouts sync.Map
+
+// RenderLayout renders the layout template for the project, the result is relative to the root
+func RenderLayout(layout string, data LayoutData) (string, error) {
+	var tmpl *template.Template
+	if cached, ok := layouts.Load(layout); ok {
+		tmpl = cached.(*template.Template)
+	} else {
+		parsed, err := ParseLayout(layout)
+		if err != nil {
+			return "", err
+		}
+		layouts.Store(layout, parsed)
+		tmpl = parsed
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("failed to render layout %q: %w", layout, err)
+	}
+
+	rendered := filepath.Clean(filepath.FromSlash(builder.String()))
+	if rendered == "." || rendered == "" {
+		return "", fmt.Errorf("layout %q renders an empty path", layout)
+	}
+	if !filepath.IsLocal(rendered) {
+		return "", fmt.Errorf("layout %q renders %s outside of the root", layout, rendered)
+	}
+	return rendered, nil
+}
+
+// layoutData returns the layout variables of a decoded project
+func (p *Project) layoutData() LayoutData {
+	namespace, repo := path.Split(p.Folders)
+
+	data := LayoutData{
+		FQDN:      p.FQDN,
+		Port:      p.Port,
+		Folders:   p.Folders,
+		Namespace: strings.TrimSuffix(namespace, "/"),
+		Repo:      repo,
+	}
+	if p.group != nil {
+		data.Group = p.group.Name
+	}
+	return data
+}
+
+// GetRoot returns the root directory of the project, the root of its group or the global one
+func (p *Project) GetRoot() string {
+	if p.group != nil && p.group.Root != "" {
+		return ExpandHome(p.group.Root)
+	}
+	return ExpandHome(DestRepoPath)
+}
+
+// GetLayout returns the layout of the project, the layout of its group or the global one
+func (p *Project) GetLayout() string {
+	if p.group != nil && p.group.Layout != "" {
+		return p.group.Layout
+	}
+	return Layout
+}
+
+// ResolvePath returns the path of the project on disk. An explicit path is used as is when absolute
+// or joined with the root when relative, otherwise the layout is rendered. Relative paths and layouts
+// must stay below the root.
+func (p *Project) ResolvePath() (string, error) {
+	root := p.GetRoot()
+	if p.Path != "" {
+		explicit := ExpandHome(p.Path)
+		if filepath.IsAbs(explicit) {
+			return filepath.Clean(explicit), nil
+		}
+		if !filepath.IsLocal(explicit) {
+			return "", fmt.Errorf("path %s leaves the root, use an absolute path", p.Path)
+		}
+		return filepath.Join(root, explicit), nil
+	}
+
+	return p.LayoutPath(root, p.GetLayout())
+}
+
+// LayoutPath returns the path of the project for the given root and layout ignoring an explicit
+// path, it's used to find repositories cloned with a previous layout
+func (p *Project) LayoutPath(root, layout string) (string, error) {
+	relative, err := RenderLayout(layout, p.layoutData())
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, relative), nil
+}
+
+// ExpandHome replaces a leading "~/" with the home directory
+func ExpandHome(path string) string {
+	if path == "~" {
+		return HomeDirectory
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(HomeDirectory, rest)
+	}
+	return path
+}
+
+// link points every project to its group, so group settings (root, layout) apply to it
+func link(groups []*Group) {
+	for _, group := range groups {
+		for _, project := range group.Projects {
+			project.group = group
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	const root = "/work"
+
+	tests := []struct {
+		name    string
+		group   string
+		layout  string // Layout of the group, the global one when empty
+		path    string // Explicit path of the project
+		want    string
+		wantErr string
+	}{
+		{name: "default layout", want: "/work/example.com/team/api"},
+		{name: "group layout", layout: "{{.Group}}/{{.Repo}}", want: "/work/backend/api"},
+		{name: "namespace", layout: "{{.Namespace}}/x-{{.Repo}}", want: "/work/team/x-api"},
+		{name: "relative path", path: "services/api", want: "/work/services/api"},
+		{name: "absolute path", path: "/srv/api", want: "/srv/api"},
+		{name: "path below the root", path: "services/../api", want: "/work/api"},
+		{name: "path leaving the root", path: "../api", wantErr: "path ../api leaves the root"},
+		{name: "path leaving the root in the middle", path: "services/../../api", wantErr: "leaves the root"},
+		{name: "layout leaving the root", layout: "../{{.Repo}}", wantErr: `layout "../{{.Repo}}" renders ../api outside of the root`},
+		{name: "group name leaving the root", group: "../..", layout: "{{.Group}}/{{.Repo}}", wantErr: "outside of the root"},
+		{name: "empty layout", layout: "{{.Port}}", wantErr: "renders an empty path"},
+		{name: "unknown field", layout: "{{.Name}}", wantErr: "invalid layout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := tt.group
+			if name == "" {
+				name = "backend"
+			}
+			g := &Group{Name: name, Root: root, Layout: tt.layout}
+			project := &Project{Url: "git@example.com:team/api.git", Path: tt.path}
+			g.AddProject(project)
+			if err := project.Decode(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.ResolvePath()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("ResolvePath() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("ResolvePath() = %q, %v, want error %q", got, err, tt.wantErr)
+			case got != filepath.FromSlash(tt.want):
+				t.Errorf("ResolvePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadChecksPaths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "repositories.yaml")
+	content := "version: 2\ngroups:\n  - name: backend\n    projects:\n      - url: git@example.com:team/api.git\n        path: ../api\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	savedFiles := Files
+	t.Cleanup(func() { Files = savedFiles })
+	Files = []string{file}
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "project git@example.com:team/api.git of group 'backend'") || !strings.Contains(err.Error(), "leaves the root") {
+		t.Fatalf("Load() error = %v, want the path leaving the root", err)
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LockTimeout is how long Save waits for another aww process to release a repositories file
+var LockTimeout = 10 * time.Second
+
+// heldLock is the lock of a repositories file held by this process
+type heldLock struct {
+	file  *os.File
+	count int // Number of holders, the file is unlocked when the last one releases it
+}
+
+var (
+	locksMu sync.Mutex
+	locks   = map[string]*heldLock{} // Held locks by absolute path of the repositories file
+)
+
+// lock takes the advisory lock of a repositories file and returns the function releasing it.
+// The lock is held on a separate file, since saving replaces the repositories file itself.
+// A lock already held by this process (see LoadLocked) is shared.
+func lock(path string) (func(), error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving path %s: %w", path, err)
+	}
+
+	locksMu.Lock()
+	defer locksMu.Unlock()
+
+	held, ok := locks[absolute]
+	if !ok {
+		file, err := acquire(path)
+		if err != nil {
+			return nil, err
+		}
+		held = &heldLock{file: file}
+		locks[absolute] = held
+	}
+	held.count++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			locksMu.Lock()
+			defer locksMu.Unlock()
+
+			held.count--
+			if held.count == 0 {
+				delete(locks, absolute)
+				unlockFile(held.file)
+				held.file.Close()
+			}
+		})
+	}, nil
+}
+
+// acquire opens the lock file of a repositories file and waits for its lock
+func acquire(path string) (*os.File, error) {
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		ok, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error locking %s: %w", path, err)
+		}
+		if ok {
+			return file, nil
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("%s is locked by another aww process", path)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
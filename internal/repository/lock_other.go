@@ -0,0 +1,15 @@
+//go:build !unix
+
+package repository
+
+import (
+	"os"
+)
+
+// tryLock always succeeds on platforms without flock, concurrent saves are only
+// detected by comparing the content of the file
+func tryLock(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(file *os.File) {}
@@ -0,0 +1,115 @@
+//go:build unix
+
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadLocked(t *testing.T) {
+	dir := t.TempDir()
+	main, included := filepath.Join(dir, "repositories.yaml"), filepath.Join(dir, "team.yaml")
+	files := map[string]string{
+		main:     "version: 2\ngroups:\n  - include: team.yaml\n  - name: backend\n    projects:\n      - url: git@host:backend/api.git\n",
+		included: "version: 2\ngroups:\n  - name: team\n    projects:\n      - url: git@host:team/web.git\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedFiles, backupPath, timeout := Files, BackupPath, LockTimeout
+	t.Cleanup(func() { Files, BackupPath, LockTimeout = savedFiles, backupPath, timeout })
+	Files, BackupPath, LockTimeout = []string{main}, filepath.Join(dir, "backups"), 200*time.Millisecond
+
+	groups, unlock, err := LoadLocked()
+	if err != nil {
+		t.Fatalf("LoadLocked() error = %v", err)
+	}
+	defer unlock()
+
+	// Another process can't take the lock of any read file
+	for path := range files {
+		if file, err := acquire(path); err == nil {
+			file.Close()
+			t.Errorf("%s isn't locked", path)
+		} else if !strings.Contains(err.Error(), "locked by another aww process") {
+			t.Errorf("acquire(%s) error = %v", path, err)
+		}
+	}
+
+	// Saving shares the locks held since the load
+	FindGroup(groups, "team").Name = "frontend"
+	FindGroup(groups, "backend").Name = "services"
+	if err := Save(groups); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	for path := range files {
+		if file, err := acquire(path); err == nil {
+			file.Close()
+			t.Errorf("%s was unlocked by Save", path)
+		}
+	}
+
+	unlock()
+	unlock() // Releasing twice is harmless
+	for path := range files {
+		file, err := acquire(path)
+		if err != nil {
+			t.Errorf("%s is still locked: %v", path, err)
+			continue
+		}
+		unlockFile(file)
+		file.Close()
+	}
+	if len(locks) != 0 {
+		t.Errorf("%d locks are still held", len(locks))
+	}
+
+	// The changes of both files were written
+	content, err := os.ReadFile(included)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "name: frontend") {
+		t.Errorf("%s wasn't saved:\n%s", included, content)
+	}
+}
+
+func TestLoadLockedWaitsForAnotherProcess(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "repositories.yaml")
+	if err := os.WriteFile(path, []byte("version: 2\ngroups:\n  - name: backend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	savedFiles, timeout := Files, LockTimeout
+	t.Cleanup(func() { Files, LockTimeout = savedFiles, timeout })
+	Files, LockTimeout = []string{path}, 100*time.Millisecond
+
+	// Lock held by another process
+	file, err := acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, _, err := LoadLocked(); err == nil || !strings.Contains(err.Error(), "locked by another aww process") {
+		t.Fatalf("LoadLocked() error = %v, want the file to be locked", err)
+	}
+	if len(locks) != 0 {
+		t.Errorf("%d locks are still held after the failed load", len(locks))
+	}
+
+	unlockFile(file)
+	_, unlock, err := LoadLocked()
+	if err != nil {
+		t.Fatalf("LoadLocked() error = %v after the lock was released", err)
+	}
+	unlock()
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ImportPath is the folder keeping the generated files of the last imports, the base of the next merge
+var ImportPath = filepath.Join(RepositoryPath, "imports")
+
+// MergeOptions select what Merge does with projects which disappeared from the generated file
+type MergeOptions struct {
+	Prune bool   // Remove the projects
+	Mark  string // Label added to the projects, when not pruned
+}
+
+// MergedProject is a project changed or reported by Merge
+type MergedProject struct {
+	Group   string
+	Project *Project
+}
+
+// MergeReport lists what Merge did
+type MergeReport struct {
+	Added       []MergedProject // New in the generated file
+	Updated     []MergedProject // Labels changed by the generator
+	Skipped     []MergedProject // Generated again but removed by hand since the last import
+	Disappeared []MergedProject // Generated by the last import, missing in the new generated file
+	Missing     []MergedProject // Never generated, kept as added by hand
+	Pruned      bool
+}
+
+// project of a merged file together with its group
+type mergeEntry struct {
+	group   *Group
+	project *Project
+}
+
+// Merge merges the newly generated groups (theirs) into the groups of the repositories file (ours),
+// base holds the groups generated by the previous import and is nil for the first import.
+// Settings made by hand (actions, paths, labels, removed projects) are kept, labels added or
+// removed by the generator since base are applied. The groups of ours are changed in place,
+// new groups are appended.
+func Merge(ours, base, theirs []*Group, options MergeOptions) ([]*Group, *MergeReport) {
+	report := &MergeReport{Pruned: options.Prune}
+	baseProjects := indexProjects(base)
+	ourProjects := indexProjects(ours)
+	theirProjects := indexProjects(theirs)
+
+	for _, theirGroup := range theirs {
+		for _, theirProject := range theirGroup.Projects {
+			key := projectKey(theirProject.Url)
+			baseEntry, inBase := baseProjects[key]
+
+			if our, ok := ourProjects[key]; ok {
+				var baseLabels []string
+				if inBase {
+					baseLabels = baseEntry.project.Labels
+				}
+				labels := mergeLabels(baseLabels, our.project.Labels, theirProject.Labels)
+				if options.Mark != "" {
+					// The project is back, it isn't marked as disappeared anymore
+					labels = slices.DeleteFunc(labels, func(label string) bool { return label == options.Mark })
+				}
+				if !slices.Equal(labels, our.project.Labels) {
+					our.project.Labels = labels
+					report.Updated = append(report.Updated, MergedProject{Group: our.group.Name, Project: our.project})
+				}
+				continue
+			}
+
+			if inBase {
+				report.Skipped = append(report.Skipped, MergedProject{Group: theirGroup.Name, Project: theirProject})
+				continue
+			}
+
+			group := FindGroup(ours, theirGroup.Name)
+			if group == nil {
+				group = &Group{
+					Name:    theirGroup.Name,
+					Labels:  theirGroup.Labels,
+					Root:    theirGroup.Root,
+					Layout:  theirGroup.Layout,
+					Actions: theirGroup.Actions,
+					Clone:   theirGroup.Clone,
+				}
+				ours = append(ours, group)
+			}
+			project := &Project{
+				Url:     theirProject.Url,
+				Path:    theirProject.Path,
+				Labels:  theirProject.Labels,
+				Actions: theirProject.Actions,
+				Clone:   theirProject.Clone,
+			}
+			group.AddProject(project)
+			ourProjects[key] = mergeEntry{group: group, project: project}
+			report.Added = append(report.Added, MergedProject{Group: group.Name, Project: project})
+		}
+	}
+
+	for _, group := range ours {
+		for _, project := range slices.Clone(group.Projects) {
+			key := projectKey(project.Url)
+			if _, ok := theirProjects[key]; ok {
+				continue
+			}
+
+			// Projects marked by a previous import disappeared before the base was generated
+			merged := MergedProject{Group: group.Name, Project: project}
+			_, inBase := baseProjects[key]
+			if !inBase && (options.Mark == "" || !slices.Contains(project.Labels, options.Mark)) {
+				report.Missing = append(report.Missing, merged)
+				continue
+			}
+
+			report.Disappeared = append(report.Disappeared, merged)
+			switch {
+			case options.Prune:
+				group.RemoveProject(project)
+			case options.Mark != "" && !slices.Contains(project.Labels, options.Mark):
+				project.Labels = append(project.Labels, options.Mark)
+			}
+		}
+	}
+
+	return ours, report
+}
+
+// indexProjects maps the projects of the groups by repository
+func indexProjects(groups []*Group) map[string]mergeEntry {
+	projects := map[string]mergeEntry{}
+	for _, group := range groups {
+		for _, project := range group.Projects {
+			projects[projectKey(project.Url)] = mergeEntry{group: group, project: project}
+		}
+	}
+	return projects
+}
+
+// mergeLabels applies the labels added and removed by the generator since base to our labels
+func mergeLabels(base, ours, theirs []string) []string {
+	var labels []string
+	for _, label := range ours {
+		if slices.Contains(base, label) && !slices.Contains(theirs, label) {
+			continue
+		}
+		labels = append(labels, label)
+	}
+	for _, label := range theirs {
+		if !slices.Contains(base, label) && !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// ImportBase returns the groups generated by the last import of the generator into the file,
+// nil when the file wasn't imported into yet
+func ImportBase(path, generator string) ([]*Group, error) {
+	base, err := importBasePath(path, generator)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(base); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return Read(base)
+}
+
+// SaveImportBase keeps the generated file as the base of the next import into the file
+func SaveImportBase(path, generator string, content []byte) error {
+	base, err := importBasePath(path, generator)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return fmt.Errorf("error creating import directory: %w", err)
+	}
+	if err := writeFile(base, content, 0644); err != nil {
+		return fmt.Errorf("error writing import base: %w", err)
+	}
+	return nil
+}
+
+func importBasePath(path, generator string) (string, error) {
+	key, err := fileKey(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ImportPath, key, generator+".yaml"), nil
+}
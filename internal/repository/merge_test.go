@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// group builds a group of projects for merge tests
+func group(name string, projects ...*Project) *Group {
+	g := &Group{Name: name}
+	for _, project := range projects {
+		g.AddProject(project)
+	}
+	return g
+}
+
+// project builds a project for merge tests
+func project(url string, labels ...string) *Project {
+	return &Project{Url: url, Labels: labels}
+}
+
+// describeGroups lists the projects of the groups as "<group>: <url> [labels]"
+func describeGroups(groups []*Group) []string {
+	var lines []string
+	for _, g := range groups {
+		for _, p := range g.Projects {
+			lines = append(lines, fmt.Sprintf("%s: %s %v", g.Name, p.Url, p.Labels))
+		}
+	}
+	return lines
+}
+
+// describeMerged lists the urls of merged projects
+func describeMerged(projects []MergedProject) string {
+	var urls []string
+	for _, merged := range projects {
+		urls = append(urls, merged.Group+":"+merged.Project.Url)
+	}
+	return strings.Join(urls, ",")
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name            string
+		ours            []*Group
+		base            []*Group
+		theirs          []*Group
+		options         MergeOptions
+		want            []string
+		wantAdded       string
+		wantUpdated     string
+		wantSkipped     string
+		wantDisappeared string
+		wantMissing     string
+	}{
+		{
+			name:        "first import",
+			ours:        []*Group{group("backend", project("git@host:backend/api.git", "go"), project("git@host:backend/manual.git"))},
+			theirs:      []*Group{group("backend", project("git@host:backend/api.git", "service"), project("git@host:backend/worker.git")), group("frontend", project("git@host:frontend/web.git", "js"))},
+			want:        []string{"backend: git@host:backend/api.git [go service]", "backend: git@host:backend/manual.git []", "backend: git@host:backend/worker.git []", "frontend: git@host:frontend/web.git [js]"},
+			wantAdded:   "backend:git@host:backend/worker.git,frontend:git@host:frontend/web.git",
+			wantUpdated: "backend:git@host:backend/api.git",
+			wantMissing: "backend:git@host:backend/manual.git",
+		},
+		{
+			name:   "nothing changed",
+			ours:   []*Group{group("backend", project("git@host:backend/api.git", "go"))},
+			base:   []*Group{group("backend", project("git@host:backend/api.git", "go"))},
+			theirs: []*Group{group("backend", project("git@host:backend/api.git", "go"))},
+			want:   []string{"backend: git@host:backend/api.git [go]"},
+		},
+		{
+			name:        "labels changed by the generator",
+			ours:        []*Group{group("backend", project("git@host:backend/api.git", "old", "mine"))},
+			base:        []*Group{group("backend", project("git@host:backend/api.git", "old"))},
+			theirs:      []*Group{group("backend", project("git@host:backend/api.git", "new"))},
+			want:        []string{"backend: git@host:backend/api.git [mine new]"},
+			wantUpdated: "backend:git@host:backend/api.git",
+		},
+		{
+			name:   "label removed by hand stays removed",
+			ours:   []*Group{group("backend", project("git@host:backend/api.git"))},
+			base:   []*Group{group("backend", project("git@host:backend/api.git", "go"))},
+			theirs: []*Group{group("backend", project("git@host:backend/api.git", "go"))},
+			want:   []string{"backend: git@host:backend/api.git []"},
+		},
+		{
+			name:   "project moved by hand stays in its group",
+			ours:   []*Group{group("platform", project("git@host:backend/api.git"))},
+			base:   []*Group{group("backend", project("git@host:backend/api.git"))},
+			theirs: []*Group{group("backend", project("git@host:backend/api.git"))},
+			want:   []string{"platform: git@host:backend/api.git []"},
+		},
+		{
+			name:   "url forms of the same repository match",
+			ours:   []*Group{group("backend", project("git@host:backend/api.git"))},
+			theirs: []*Group{group("backend", project("https://host/backend/api"))},
+			want:   []string{"backend: git@host:backend/api.git []"},
+		},
+		{
+			name:        "project removed by hand is skipped",
+			ours:        []*Group{group("backend", project("git@host:backend/api.git"))},
+			base:        []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git"))},
+			theirs:      []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git"))},
+			want:        []string{"backend: git@host:backend/api.git []"},
+			wantSkipped: "backend:git@host:backend/old.git",
+		},
+		{
+			name:            "disappeared project is kept",
+			ours:            []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git"))},
+			base:            []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git"))},
+			theirs:          []*Group{group("backend", project("git@host:backend/api.git"))},
+			want:            []string{"backend: git@host:backend/api.git []", "backend: git@host:backend/old.git []"},
+			wantDisappeared: "backend:git@host:backend/old.git",
+		},
+		{
+			name:            "disappeared project is pruned",
+			ours:            []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git"))},
+			base:            []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git"))},
+			theirs:          []*Group{group("backend", project("git@host:backend/api.git"))},
+			options:         MergeOptions{Prune: true},
+			want:            []string{"backend: git@host:backend/api.git []"},
+			wantDisappeared: "backend:git@host:backend/old.git",
+		},
+		{
+			name:            "disappeared project is marked",
+			ours:            []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git", "go"))},
+			base:            []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git"))},
+			theirs:          []*Group{group("backend", project("git@host:backend/api.git"))},
+			options:         MergeOptions{Mark: "gone"},
+			want:            []string{"backend: git@host:backend/api.git []", "backend: git@host:backend/old.git [go gone]"},
+			wantDisappeared: "backend:git@host:backend/old.git",
+		},
+		{
+			name:            "marked project is still reported and marked once",
+			ours:            []*Group{group("backend", project("git@host:backend/api.git"), project("git@host:backend/old.git", "gone"))},
+			base:            []*Group{group("backend", project("git@host:backend/api.git"))},
+			theirs:          []*Group{group("backend", project("git@host:backend/api.git"))},
+			options:         MergeOptions{Mark: "gone"},
+			want:            []string{"backend: git@host:backend/api.git []", "backend: git@host:backend/old.git [gone]"},
+			wantDisappeared: "backend:git@host:backend/old.git",
+		},
+		{
+			name:        "marked project is back",
+			ours:        []*Group{group("backend", project("git@host:backend/old.git", "go", "gone"))},
+			base:        []*Group{group("backend")},
+			theirs:      []*Group{group("backend", project("git@host:backend/old.git", "go"))},
+			options:     MergeOptions{Mark: "gone"},
+			want:        []string{"backend: git@host:backend/old.git [go]"},
+			wantUpdated: "backend:git@host:backend/old.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, report := Merge(tt.ours, tt.base, tt.theirs, tt.options)
+
+			if got := describeGroups(merged); !slices.Equal(got, tt.want) {
+				t.Errorf("merged groups:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, check := range []struct {
+				name     string
+				projects []MergedProject
+				want     string
+			}{
+				{"added", report.Added, tt.wantAdded},
+				{"updated", report.Updated, tt.wantUpdated},
+				{"skipped", report.Skipped, tt.wantSkipped},
+				{"disappeared", report.Disappeared, tt.wantDisappeared},
+				{"missing", report.Missing, tt.wantMissing},
+			} {
+				if got := describeMerged(check.projects); got != check.want {
+					t.Errorf("%s projects = %q, want %q", check.name, got, check.want)
+				}
+			}
+			if report.Pruned != tt.options.Prune {
+				t.Errorf("report.Pruned = %v, want %v", report.Pruned, tt.options.Prune)
+			}
+		})
+	}
+}
+
+func TestMergeNewGroup(t *testing.T) {
+	push, depth := true, 1
+	theirs := []*Group{{
+		Name:    "frontend",
+		Labels:  []string{"js"},
+		Root:    "~/web",
+		Layout:  "{{.Folders}}",
+		Actions: &GroupActions{Commit: "update", Push: &push},
+		Clone:   &CloneOptions{Depth: &depth},
+	}}
+	theirs[0].AddProject(&Project{Url: "git@host:frontend/web.git", Path: "web", Labels: []string{"app"}, Actions: &ProjectActions{Commit: "fix"}, Clone: &CloneOptions{Sparse: []string{"src"}}})
+
+	merged, _ := Merge([]*Group{group("backend")}, nil, theirs, MergeOptions{})
+
+	if len(merged) != 2 {
+		t.Fatalf("got %d groups, want 2", len(merged))
+	}
+	g, want := merged[1], theirs[0]
+	if g == want {
+		t.Fatal("the generated group was added instead of a copy")
+	}
+	if g.Name != want.Name || !slices.Equal(g.Labels, want.Labels) || g.Root != want.Root || g.Layout != want.Layout || g.Actions != want.Actions || g.Clone != want.Clone {
+		t.Errorf("group = %+v, want the settings of %+v", g, want)
+	}
+
+	if len(g.Projects) != 1 {
+		t.Fatalf("got %d projects, want 1", len(g.Projects))
+	}
+	p, wantProject := g.Projects[0], want.Projects[0]
+	if p.Url != wantProject.Url || p.Path != wantProject.Path || !slices.Equal(p.Labels, wantProject.Labels) || p.Actions != wantProject.Actions || p.Clone != wantProject.Clone {
+		t.Errorf("project = %+v, want the settings of %+v", p, wantProject)
+	}
+	if p.group != g {
+		t.Error("project isn't linked to its new group")
+	}
+}
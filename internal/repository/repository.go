@@ -1,12 +1,14 @@
 package repository
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,59 +20,651 @@ var (
 	RepositoryPath = filepath.Join(HomeDirectory, ".aww")
 	// RepositoryFilePath is the path to the configuration file.
 	RepositoryFilePath = filepath.Join(RepositoryPath, "repositories.yaml")
+	// Files are the repositories files merged by Load, RepositoryFilePath when empty
+	Files []string
 	// Main root folder
 	DestRepoPath = filepath.Join(HomeDirectory, "aww")
 )
 
+// entry is an item of a repositories file, either a group or an include directive
+type entry struct {
+	include  *yaml.Node              // Patterns of an include directive, kept as written
+	group    *Group                  // Current state of the group
+	node     *yaml.Node              // Node of the group in the file, nil until the group is written
+	projects map[*Project]*yaml.Node // Nodes of the projects of the group in the file
+}
+
+// source is a loaded repositories file
+type source struct {
+	path     string
+	content  []byte     // Content of the file as last read or written
+	version  int        // Version of the format of the file
+	sequence *yaml.Node // List of groups of the file, nil when the file has none
+	block    bool       // The file is a block sequence, which can be edited in place
+	indent   int        // Indentation of the items of the file
+	entries  []*entry
+	removed  []*entry // Groups removed since the file was read
+}
+
+// sources are the files read by the last Load, Save writes groups back to them
+var sources []*source
+
+// loader reads repositories files and the files they include
+type loader struct {
+	sources []*source
+	groups  []*Group
+	visited map[string]string // Absolute path of every read file to the file including it
+	locked  bool              // Files are locked before being read
+	unlocks []func()
+}
+
+// unlock releases the locks taken by the loader
+func (l *loader) unlock() {
+	for _, unlock := range l.unlocks {
+		unlock()
+	}
+}
+
 // LoadTemplate loads the template from the file.
 func Load() ([]*Group, error) {
-	template := []*Group{}
+	return load(&loader{visited: map[string]string{}})
+}
+
+// LoadLocked loads the groups like Load and keeps every read file locked until the returned function
+// is called, so no other aww process changes the files between the load and the Save of a command
+func LoadLocked() ([]*Group, func(), error) {
+	l := &loader{visited: map[string]string{}, locked: true}
+	groups, err := load(l)
+	if err != nil {
+		l.unlock()
+		return nil, nil, err
+	}
+	return groups, l.unlock, nil
+}
+
+func load(l *loader) ([]*Group, error) {
+	for _, path := range filePaths() {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			// Return error if the file doesn't exist
+			return nil, fmt.Errorf("%s not found", path)
+		}
+		if err := l.load(path, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(l.groups) == 0 {
+		return nil, fmt.Errorf("no groups found")
+	}
+
+	if err := checkDuplicates(l.groups); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseLayout(Layout); err != nil {
+		return nil, err
+	}
+	for _, group := range l.groups {
+		if group.Layout == "" {
+			continue
+		}
+		if _, err := ParseLayout(group.Layout); err != nil {
+			return nil, fmt.Errorf("group %s: %w", group.Name, err)
+		}
+	}
+	link(l.groups)
 
-	templateFile, err := os.Open(RepositoryFilePath)
-	if errors.Is(err, os.ErrNotExist) {
-		// Return error if the file doesn't exist
-		return nil, fmt.Errorf("%s not found", RepositoryFilePath)
-	} else if err != nil {
-		return nil, fmt.Errorf("error opening template file: %w", err)
+	if err := checkPaths(l.groups); err != nil {
+		return nil, err
 	}
-	defer templateFile.Close()
 
-	byteValue, err := io.ReadAll(templateFile)
+	sources = l.sources
+	return l.groups, nil
+}
+
+// Read returns the groups of a repositories file and the files it includes. Unlike Load it
+// doesn't check the groups and the file isn't written by Save.
+func Read(path string) ([]*Group, error) {
+	l := &loader{visited: map[string]string{}}
+	if err := l.load(ExpandHome(path), ""); err != nil {
+		return nil, err
+	}
+	link(l.groups)
+	return l.groups, nil
+}
+
+// load reads a repositories file and, in place of its include directives, the included files
+func (l *loader) load(path, includedBy string) error {
+	absolute, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("error reading template file: %w", err)
+		return fmt.Errorf("error resolving path %s: %w", path, err)
 	}
+	if previous, ok := l.visited[absolute]; ok {
+		return fmt.Errorf("%s is loaded more than once (from %s and %s)", path, describeIncluder(previous), describeIncluder(includedBy))
+	}
+	l.visited[absolute] = includedBy
 
-	if err := yaml.Unmarshal(byteValue, &template); err != nil {
-		return nil, fmt.Errorf("error parsing template file: %w", err)
+	if l.locked {
+		unlock, err := lock(path)
+		if err != nil {
+			return err
+		}
+		l.unlocks = append(l.unlocks, unlock)
 	}
 
-	if len(template) == 0 {
-		return nil, fmt.Errorf("no groups found")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error opening template file: %w", err)
+	}
+
+	src := &source{path: path}
+	items, err := src.parse(content)
+	if err != nil {
+		return err
 	}
+	l.sources = append(l.sources, src)
 
-	return template, nil
+	for _, item := range items {
+		if include := includeNode(item); include != nil {
+			src.entries = append(src.entries, &entry{include: include})
+
+			patterns, err := includePatterns(include)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, include.Line, err)
+			}
+
+			for _, pattern := range patterns {
+				if err := l.include(pattern, path); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		group := &Group{}
+		if err := item.Decode(group); err != nil {
+			return fmt.Errorf("error parsing template file %s: %w", path, err)
+		}
+		group.file = path
+
+		e := &entry{group: group, node: item}
+		e.bindProjects()
+		src.entries = append(src.entries, e)
+		l.groups = append(l.groups, group)
+	}
+
+	return nil
 }
 
-// Save updates the repository file.
+// include loads the files matching the pattern
+func (l *loader) include(pattern, includedBy string) error {
+	matches, err := resolveInclude(pattern, includedBy)
+	if err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		if err := l.load(match, includedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filePaths returns the repositories files given on the command line, RepositoryFilePath when none
+func filePaths() []string {
+	if len(Files) == 0 {
+		return []string{RepositoryFilePath}
+	}
+	paths := make([]string, len(Files))
+	for i, path := range Files {
+		paths[i] = ExpandHome(path)
+	}
+	return paths
+}
+
+// MainFile returns the repositories file new groups are added to, the first file given on the command line
+func MainFile() string {
+	return filePaths()[0]
+}
+
+// includePatterns returns the patterns of an include directive, a single path or a list of paths
+func includePatterns(include *yaml.Node) ([]string, error) {
+	var patterns []string
+	if err := include.Decode(&patterns); err != nil {
+		var pattern string
+		if err := include.Decode(&pattern); err != nil {
+			return nil, fmt.Errorf("include must be a path or a list of paths")
+		}
+		patterns = []string{pattern}
+	}
+	return patterns, nil
+}
+
+// resolveInclude returns the files matching the pattern, relative patterns are resolved against the including file
+func resolveInclude(pattern, includedBy string) ([]string, error) {
+	pattern = ExpandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(includedBy), pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %s in %s: %w", pattern, includedBy, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return nil, fmt.Errorf("included file %s not found (in %s)", pattern, includedBy)
+	}
+	return matches, nil
+}
+
+// includeNode returns the value of the include key when the item is an include directive
+func includeNode(item *yaml.Node) *yaml.Node {
+	if item.Kind != yaml.MappingNode || len(item.Content) != 2 || item.Content[0].Value != "include" {
+		return nil
+	}
+	return item.Content[1]
+}
+
+// describeIncluder names the origin of a loaded file in error messages
+func describeIncluder(includedBy string) string {
+	if includedBy == "" {
+		return "the command line"
+	}
+	return includedBy
+}
+
+// checkDuplicates reports group names and project urls declared more than once across all files
+func checkDuplicates(groups []*Group) error {
+	var errs []error
+	groupFiles := map[string]string{}
+	projectGroups := map[string]*Group{}
+
+	for _, group := range groups {
+		if file, ok := groupFiles[group.Name]; ok {
+			errs = append(errs, fmt.Errorf("duplicate group '%s' in %s and %s", group.Name, file, group.file))
+		} else {
+			groupFiles[group.Name] = group.file
+		}
+
+		for _, project := range group.Projects {
+			key := projectKey(project.Url)
+			if other, ok := projectGroups[key]; ok {
+				errs = append(errs, fmt.Errorf("duplicate project %s in group '%s' (%s) and group '%s' (%s)", project.Url, other.Name, other.file, group.Name, group.file))
+				continue
+			}
+			projectGroups[key] = group
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkPaths checks that the path of every project can be resolved, projects with invalid urls
+// are left to the commands which report them
+func checkPaths(groups []*Group) error {
+	var errs []error
+	for _, group := range groups {
+		for _, project := range group.Projects {
+			if project.Decode() != nil {
+				continue
+			}
+			if _, err := project.ResolvePath(); err != nil {
+				errs = append(errs, fmt.Errorf("project %s of group '%s' (%s): %w", project.Url, group.Name, group.file, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// projectKey identifies the repository behind an url, different url forms of the same repository share it
+func projectKey(url string) string {
+	remote, err := ParseURL(url)
+	if err != nil {
+		return url
+	}
+	return remote.FQDN() + "/" + remote.Path
+}
+
+// FileChange is the new content of a repositories file
+type FileChange struct {
+	Path   string
+	Before []byte // Current content, nil for a new file
+	After  []byte
+}
+
+// Save updates the repository files, every group is written back to the file it was loaded from.
+// New groups are added to the first file, groups missing in repositories are removed.
 func Save(repositories []*Group) error {
-	// Open the file for writing
-	file, err := os.OpenFile(RepositoryFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	assign(repositories)
+
+	for _, src := range sources {
+		if err := src.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Changes returns the contents Save would write for the groups, without writing them.
+// Files which wouldn't change are left out.
+func Changes(repositories []*Group) ([]FileChange, error) {
+	assign(repositories)
+
+	var changes []FileChange
+	for _, src := range sources {
+		content, _, err := src.rendered()
+		if err != nil {
+			return nil, err
+		}
+		if src.content != nil && bytes.Equal(content, src.content) {
+			continue
+		}
+		changes = append(changes, FileChange{Path: src.path, Before: src.content, After: content})
+	}
+	return changes, nil
+}
+
+// assign binds the groups to the files they are written to
+func assign(repositories []*Group) {
+	if len(sources) == 0 {
+		sources = []*source{{path: RepositoryFilePath, version: CurrentVersion}}
+	}
+
+	keep := map[*Group]bool{}
+	for _, group := range repositories {
+		keep[group] = true
+	}
+
+	known := map[*Group]bool{}
+	for _, src := range sources {
+		entries := src.entries[:0]
+		for _, e := range src.entries {
+			if e.group != nil {
+				if !keep[e.group] {
+					if e.node != nil {
+						src.removed = append(src.removed, e)
+					}
+					continue
+				}
+				known[e.group] = true
+			}
+			entries = append(entries, e)
+		}
+		src.entries = entries
+	}
+	for _, group := range repositories {
+		if !known[group] {
+			group.file = sources[0].path
+			sources[0].entries = append(sources[0].entries, &entry{group: group})
+		}
+	}
+}
+
+// parse reads the items of the file content
+func (s *source) parse(content []byte) ([]*yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("error parsing template file %s: %w", s.path, err)
+	}
+
+	sequence, version, err := groupsNode(&root)
 	if err != nil {
-		return fmt.Errorf("error opening template file for writing: %w", err)
+		return nil, fmt.Errorf("error parsing template file %s: %w", s.path, err)
 	}
-	defer file.Close()
 
-	// Write the template to the file
-	encoder := yaml.NewEncoder(file)
-	encoder.SetIndent(2)
+	s.content = content
+	s.version = version
+	s.sequence = sequence
+	s.block = false
+	if sequence == nil {
+		return nil, nil
+	}
 
-	if err := encoder.Encode(repositories); err != nil {
-		return fmt.Errorf("error encoding template content: %w", err)
+	if len(sequence.Content) > 0 && sequence.Style&yaml.FlowStyle == 0 {
+		s.block = true
+		_, s.indent, _ = newDocument(content).item(sequence.Content[0])
 	}
 
+	return sequence.Content, nil
+}
+
+// save writes the changes of the groups to the file. Changes are applied in place, keeping
+// comments and formatting, files which didn't change aren't touched.
+func (s *source) save() error {
+	content, rewritten, err := s.rendered()
+	if err != nil {
+		return err
+	}
+	if rewritten {
+		log.Warn().Str("file", s.path).Msg("Changes can't be applied in place, the file is written again without comments")
+	}
+
+	if s.content != nil && bytes.Equal(content, s.content) {
+		return nil
+	}
+
+	if err := replaceFile(s.path, s.content, content); err != nil {
+		return err
+	}
+
+	// Positions of the nodes moved, the file is bound again to its groups
+	items, err := s.parse(content)
+	if err != nil {
+		return err
+	}
+	s.removed = nil
+	s.bind(items)
+
+	return nil
+}
+
+// replaceFile writes the content to the file while holding its lock, the previous content is
+// backed up and the file is replaced atomically. The file must still hold the loaded content,
+// changes of another process since it was read would be lost otherwise.
+func replaceFile(path string, loaded, content []byte) error {
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("error opening template file: %w", err)
+	case loaded != nil && !bytes.Equal(current, loaded):
+		return fmt.Errorf("%s was changed by another process since it was loaded, run the command again", path)
+	default:
+		if err := backup(path, current); err != nil {
+			return err
+		}
+	}
+
+	// Write the template to the file
+	if err := writeFile(path, content, fileMode(path)); err != nil {
+		return fmt.Errorf("error opening template file for writing: %w", err)
+	}
 	return nil
 }
 
+// rendered returns the new content of the file, rewritten when the changes couldn't be applied in place
+func (s *source) rendered() ([]byte, bool, error) {
+	content, err := s.render()
+	if errors.Is(err, errRewrite) {
+		content, err = s.encode()
+		return content, true, err
+	}
+	return content, false, err
+}
+
+// render applies the changes of the groups to the content of the file
+func (s *source) render() ([]byte, error) {
+	if !s.block {
+		return s.encode()
+	}
+
+	doc := newDocument(s.content)
+	for _, e := range s.removed {
+		doc.removeItem(e.node)
+	}
+
+	var added []any
+	for _, e := range s.entries {
+		switch {
+		case e.include != nil:
+		case e.node == nil:
+			added = append(added, e.group)
+		default:
+			if err := e.update(doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(added) > 0 {
+		// Groups of a document are added after its last group, other keys may follow the list
+		line := doc.lineCount()
+		if s.version > 1 {
+			_, _, line = doc.item(s.sequence.Content[len(s.sequence.Content)-1])
+		}
+		if err := doc.appendItems(line, s.indent, added); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc.apply()
+}
+
+// encode renders all entries of the file from scratch
+func (s *source) encode() ([]byte, error) {
+	items := make([]any, 0, len(s.entries))
+	for _, e := range s.entries {
+		if e.include != nil {
+			items = append(items, map[string]*yaml.Node{"include": e.include})
+		} else {
+			items = append(items, e.group)
+		}
+	}
+
+	var value any = items
+	if s.version > 1 {
+		value = fileDocument{Version: s.version, Groups: items}
+	}
+	content, err := render(value)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// bind points the entries to the nodes of a freshly written file, which holds them in the same order
+func (s *source) bind(items []*yaml.Node) {
+	for i, e := range s.entries {
+		if i >= len(items) {
+			break
+		}
+		if e.group != nil {
+			e.node = items[i]
+			e.bindProjects()
+		}
+	}
+}
+
+// bindProjects points the projects of the group to their nodes, matching them by url
+func (e *entry) bindProjects() {
+	e.projects = map[*Project]*yaml.Node{}
+
+	_, sequence := lookup(e.node, "projects")
+	if sequence == nil || sequence.Kind != yaml.SequenceNode {
+		return
+	}
+
+	nodes := map[string]*yaml.Node{}
+	for _, node := range sequence.Content {
+		_, url := lookup(node, "url")
+		if url != nil {
+			nodes[projectKey(url.Value)] = node
+		}
+	}
+	for _, project := range e.group.Projects {
+		if node, ok := nodes[projectKey(project.Url)]; ok {
+			e.projects[project] = node
+		}
+	}
+}
+
+// update edits the group in the document where it changed
+func (e *entry) update(doc *document) error {
+	original := &Group{}
+	if err := e.node.Decode(original); err != nil {
+		return fmt.Errorf("error parsing group: %w", err)
+	}
+
+	if e.node.Kind != yaml.MappingNode || e.node.Style&yaml.FlowStyle != 0 {
+		same, err := equalValues(original, e.group)
+		if err != nil || same {
+			return err
+		}
+		return doc.replaceItem(e.node, e.group)
+	}
+
+	_, sequence := lookup(e.node, "projects")
+	if sequence == nil || sequence.Kind != yaml.SequenceNode || sequence.Style&yaml.FlowStyle != 0 ||
+		len(sequence.Content) == 0 || len(e.group.Projects) == 0 {
+		// Projects are replaced as a whole
+		return doc.updateMapping(e.node, original, e.group)
+	}
+
+	present := map[*Project]bool{}
+	for _, project := range e.group.Projects {
+		present[project] = true
+	}
+	for project, node := range e.projects {
+		if !present[project] {
+			doc.removeItem(node)
+		}
+	}
+
+	var added []any
+	for _, project := range e.group.Projects {
+		node, ok := e.projects[project]
+		if !ok {
+			added = append(added, project)
+			continue
+		}
+
+		before := &Project{}
+		if err := node.Decode(before); err != nil {
+			return fmt.Errorf("error parsing project: %w", err)
+		}
+		if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 {
+			same, err := equalValues(before, project)
+			if err != nil {
+				return err
+			}
+			if !same {
+				if err := doc.replaceItem(node, project); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := doc.updateMapping(node, before, project); err != nil {
+			return err
+		}
+	}
+
+	if len(added) > 0 {
+		_, indent, end := doc.item(sequence.Content[len(sequence.Content)-1])
+		if err := doc.appendItems(end, indent, added); err != nil {
+			return err
+		}
+	}
+
+	return doc.updateMapping(e.node, original, e.group, "projects")
+}
+
 // Init checking if repository path is exists
 func Init() error {
 	if _, err := os.Stat(RepositoryPath); errors.Is(err, os.ErrNotExist) {
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// repositoriesFiles writes the files below a temporary folder, makes the first one the repositories
+// file of Load and restores the globals after the test. files alternate paths and contents.
+func repositoriesFiles(t *testing.T, files ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for i := 0; i+1 < len(files); i += 2 {
+		path := filepath.Join(dir, filepath.FromSlash(files[i]))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedFiles, backupPath, savedSources := Files, BackupPath, sources
+	t.Cleanup(func() { Files, BackupPath, sources = savedFiles, backupPath, savedSources })
+	Files, BackupPath = []string{filepath.Join(dir, filepath.FromSlash(files[0]))}, filepath.Join(dir, "backups")
+	return dir
+}
+
+// groupFile builds a repositories file with a group per name, each with a project named after the group
+func groupFile(names ...string) string {
+	var builder strings.Builder
+	builder.WriteString("version: 2\ngroups:\n")
+	for _, name := range names {
+		if include, ok := strings.CutPrefix(name, "include "); ok {
+			builder.WriteString("  - include: " + include + "\n")
+			continue
+		}
+		builder.WriteString("  - name: " + name + "\n    projects:\n      - url: git@host:" + name + "/repo.git\n")
+	}
+	return builder.String()
+}
+
+func TestLoadIncludes(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string // Paths and contents, the first file is loaded
+		want    []string // Groups in load order
+		wantErr string
+	}{
+		{
+			name:  "relative path",
+			files: []string{"repositories.yaml", groupFile("main", "include teams/a.yaml", "last"), "teams/a.yaml", groupFile("a")},
+			want:  []string{"main", "a", "last"},
+		},
+		{
+			name: "relative to the including file",
+			files: []string{
+				"repositories.yaml", groupFile("include teams/a.yaml"),
+				"teams/a.yaml", groupFile("a", "include ../common/c.yaml"),
+				"common/c.yaml", groupFile("c"),
+			},
+			want: []string{"a", "c"},
+		},
+		{
+			name:  "glob",
+			files: []string{"repositories.yaml", groupFile("include teams/*.yaml"), "teams/b.yaml", groupFile("b"), "teams/a.yaml", groupFile("a"), "teams/notes.txt", "not yaml"},
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "list of patterns",
+			files: []string{"repositories.yaml", "version: 2\ngroups:\n  - include: [b.yaml, a.yaml]\n", "a.yaml", groupFile("a"), "b.yaml", groupFile("b")},
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "glob without matches",
+			files: []string{"repositories.yaml", groupFile("main", "include teams/*.yaml")},
+			want:  []string{"main"},
+		},
+		{
+			name:    "missing file",
+			files:   []string{"repositories.yaml", groupFile("main", "include teams/a.yaml")},
+			wantErr: "teams/a.yaml not found (in ",
+		},
+		{
+			name:    "file included twice",
+			files:   []string{"repositories.yaml", groupFile("include a.yaml", "include ./a.yaml"), "a.yaml", groupFile("a")},
+			wantErr: "a.yaml is loaded more than once",
+		},
+		{
+			name:    "file including itself",
+			files:   []string{"repositories.yaml", groupFile("main", "include repositories.yaml")},
+			wantErr: "repositories.yaml is loaded more than once",
+		},
+		{
+			name:    "invalid include",
+			files:   []string{"repositories.yaml", "version: 2\ngroups:\n  - include: {path: a.yaml}\n"},
+			wantErr: "repositories.yaml:3: include must be a path or a list of paths",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repositoriesFiles(t, tt.files...)
+
+			groups, err := Load()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Load() error = %v", err)
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(filepath.ToSlash(err.Error()), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			var names []string
+			for _, group := range groups {
+				names = append(names, group.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("Load() groups = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr []string // Parts of the error, empty when loading succeeds
+	}{
+		{
+			name:  "distinct",
+			files: []string{"repositories.yaml", groupFile("a", "include b.yaml"), "b.yaml", groupFile("b")},
+		},
+		{
+			name:    "group in the same file",
+			files:   []string{"repositories.yaml", groupFile("a", "a")},
+			wantErr: []string{"duplicate group 'a' in ", "repositories.yaml and "},
+		},
+		{
+			name:    "group in an included file",
+			files:   []string{"repositories.yaml", groupFile("a", "include b.yaml"), "b.yaml", groupFile("a")},
+			wantErr: []string{"duplicate group 'a' in ", "repositories.yaml and ", "b.yaml"},
+		},
+		{
+			name:    "same url",
+			files:   []string{"repositories.yaml", "version: 2\ngroups:\n  - name: a\n    projects:\n      - url: git@host:team/api.git\n  - name: b\n    projects:\n      - url: git@host:team/api.git\n"},
+			wantErr: []string{"duplicate project git@host:team/api.git in group 'a'", "and group 'b'"},
+		},
+		{
+			name:    "other form of the url",
+			files:   []string{"repositories.yaml", "version: 2\ngroups:\n  - name: a\n    projects:\n      - url: git@host:team/api.git\n  - include: b.yaml\n", "b.yaml", "version: 2\ngroups:\n  - name: b\n    projects:\n      - url: https://host:8443/team/api\n"},
+			wantErr: []string{"duplicate project https://host:8443/team/api in group 'a'", "and group 'b'", "b.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repositoriesFiles(t, tt.files...)
+
+			_, err := Load()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Load() succeeded, want an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSaveToSourceFiles(t *testing.T) {
+	dir := repositoriesFiles(t,
+		"repositories.yaml", groupFile("main", "include teams/*.yaml"),
+		"teams/a.yaml", groupFile("a"),
+		"teams/b.yaml", groupFile("b"),
+	)
+	read := func(path string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	groups, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A project added to a, b renamed, main removed and a new group
+	FindGroup(groups, "a").AddProject(&Project{Url: "git@host:a/new.git"})
+	FindGroup(groups, "b").Name = "renamed"
+	groups = slices.DeleteFunc(groups, func(group *Group) bool { return group.Name == "main" })
+	groups = append(groups, &Group{Name: "added"})
+	if err := Save(groups); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{"repositories.yaml", []string{"include: teams/*.yaml", "name: added"}, []string{"name: main", "name: a\n", "name: renamed"}},
+		{"teams/a.yaml", []string{"name: a", "url: git@host:a/repo.git", "url: git@host:a/new.git"}, []string{"added", "renamed"}},
+		{"teams/b.yaml", []string{"name: renamed", "url: git@host:b/repo.git"}, []string{"name: b\n", "added"}},
+	}
+	for _, tt := range tests {
+		content := read(tt.file)
+		for _, want := range tt.want {
+			if !strings.Contains(content, want) {
+				t.Errorf("%s doesn't contain %q:\n%s", tt.file, want, content)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(content, notWant) {
+				t.Errorf("%s contains %q:\n%s", tt.file, notWant, content)
+			}
+		}
+	}
+
+	// The saved files load the same groups again
+	groups, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v after Save", err)
+	}
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	if want := []string{"a", "renamed", "added"}; !slices.Equal(names, want) {
+		t.Errorf("groups after Save = %q, want %q", names, want)
+	}
+}
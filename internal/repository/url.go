@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// LocalFQDN is the host folder of repositories cloned from file:// urls
+const LocalFQDN = "local"
+
+// Supported url schemes
+var schemes = map[string]bool{
+	"ssh":     true,
+	"git+ssh": true,
+	"ssh+git": true,
+	"git":     true,
+	"http":    true,
+	"https":   true,
+	"file":    true,
+}
+
+// scp-like syntax: [user@]host:path, the path must not start with '/' followed by another '/'
+var scpPattern = regexp.MustCompile(`^(?:([^@/]+)@)?([a-zA-Z0-9.-]+):([^/].*|/[^/].*)$`)
+
+// RemoteURL is a parsed git remote url
+type RemoteURL struct {
+	Scheme string // ssh, https, http, git or file, "ssh" for the scp-like syntax
+	User   string
+	Host   string // Empty for file urls
+	Port   string // Empty when default
+	Path   string // Path of the repository without leading '/' and trailing ".git"
+}
+
+// ParseURL parses all common forms of git remote urls:
+//
+//	git@host:group/repo.git
+//	ssh://git@host:2222/group/repo.git
+//	https://host/group/repo(.git)
+//	git://host/group/repo.git
+//	file:///srv/git/repo.git
+func ParseURL(raw string) (*RemoteURL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("url cannot be empty")
+	}
+
+	var remote *RemoteURL
+	if strings.Contains(raw, "://") {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url: %w", err)
+		}
+
+		scheme := strings.ToLower(parsed.Scheme)
+		if !schemes[scheme] {
+			return nil, fmt.Errorf("unsupported url scheme '%s'", parsed.Scheme)
+		}
+		if strings.Contains(scheme, "ssh") {
+			scheme = "ssh"
+		}
+
+		remote = &RemoteURL{
+			Scheme: scheme,
+			User:   parsed.User.Username(),
+			Host:   strings.ToLower(parsed.Hostname()),
+			Port:   parsed.Port(),
+			Path:   parsed.Path,
+		}
+
+		if scheme == "file" {
+			if remote.Host != "" && remote.Host != "localhost" {
+				return nil, fmt.Errorf("file urls must point to a local path (file:///path/to/repo)")
+			}
+			remote.Host = ""
+		} else if remote.Host == "" {
+			return nil, fmt.Errorf("url has no host")
+		}
+	} else {
+		submatches := scpPattern.FindStringSubmatch(raw)
+		if submatches == nil {
+			return nil, fmt.Errorf("invalid git url provided (examples: git@gitlab.com:goodgroup/goodrepo.git, https://gitlab.com/goodgroup/goodrepo.git)")
+		}
+
+		remote = &RemoteURL{
+			Scheme: "ssh",
+			User:   submatches[1],
+			Host:   strings.ToLower(submatches[2]),
+			Path:   submatches[3],
+		}
+	}
+
+	// A host starting with '-' would be taken as an option by ssh (e.g. -oProxyCommand=...)
+	if strings.HasPrefix(remote.Host, "-") {
+		return nil, fmt.Errorf("url host '%s' must not start with '-'", remote.Host)
+	}
+
+	remote.Path = strings.TrimSuffix(strings.Trim(remote.Path, "/"), ".git")
+	if remote.Path == "" {
+		return nil, fmt.Errorf("url has no repository path")
+	}
+	for _, segment := range strings.Split(remote.Path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("url path '%s' is not a clean path", remote.Path)
+		}
+	}
+
+	return remote, nil
+}
+
+// FQDN returns the host folder of the repository. The port isn't part of the identity of a repository,
+// urls with different ports of a host (e.g. ssh on 2222 and https) are the same repository.
+func (u *RemoteURL) FQDN() string {
+	if u.Scheme == "file" {
+		return LocalFQDN
+	}
+	return u.Host
+}
+
+// Folders returns the path of the repository below the host folder
+func (u *RemoteURL) Folders() string {
+	return u.Path
+}
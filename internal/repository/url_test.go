@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url         string
+		want        RemoteURL
+		wantFQDN    string
+		wantFolders string
+	}{
+		// scp-like
+		{"git@gitlab.com:group/repo.git", RemoteURL{Scheme: "ssh", User: "git", Host: "gitlab.com", Path: "group/repo"}, "gitlab.com", "group/repo"},
+		{"git@GitLab.com:Group/Repo", RemoteURL{Scheme: "ssh", User: "git", Host: "gitlab.com", Path: "Group/Repo"}, "gitlab.com", "Group/Repo"},
+		{"gitlab.com:group/sub/repo.git", RemoteURL{Scheme: "ssh", Host: "gitlab.com", Path: "group/sub/repo"}, "gitlab.com", "group/sub/repo"},
+		{"git@host:/srv/repo.git", RemoteURL{Scheme: "ssh", User: "git", Host: "host", Path: "srv/repo"}, "host", "srv/repo"},
+		{"  git@gitlab.com:group/repo.git\n", RemoteURL{Scheme: "ssh", User: "git", Host: "gitlab.com", Path: "group/repo"}, "gitlab.com", "group/repo"},
+		// ssh, with and without port
+		{"ssh://git@gitlab.com/group/repo.git", RemoteURL{Scheme: "ssh", User: "git", Host: "gitlab.com", Path: "group/repo"}, "gitlab.com", "group/repo"},
+		{"ssh://git@gitlab.example.com:2222/group/repo.git", RemoteURL{Scheme: "ssh", User: "git", Host: "gitlab.example.com", Port: "2222", Path: "group/repo"}, "gitlab.example.com", "group/repo"},
+		{"git+ssh://git@host:22/repo.git", RemoteURL{Scheme: "ssh", User: "git", Host: "host", Port: "22", Path: "repo"}, "host", "repo"},
+		{"ssh+git://host/repo.git", RemoteURL{Scheme: "ssh", Host: "host", Path: "repo"}, "host", "repo"},
+		// http(s) and git
+		{"https://gitlab.com/group/repo.git", RemoteURL{Scheme: "https", Host: "gitlab.com", Path: "group/repo"}, "gitlab.com", "group/repo"},
+		{"https://user@gitlab.com/group/repo/", RemoteURL{Scheme: "https", User: "user", Host: "gitlab.com", Path: "group/repo"}, "gitlab.com", "group/repo"},
+		{"HTTP://host:8080/repo", RemoteURL{Scheme: "http", Host: "host", Port: "8080", Path: "repo"}, "host", "repo"},
+		{"git://host/group/repo.git", RemoteURL{Scheme: "git", Host: "host", Path: "group/repo"}, "host", "group/repo"},
+		// file
+		{"file:///srv/git/repo.git", RemoteURL{Scheme: "file", Path: "srv/git/repo"}, LocalFQDN, "srv/git/repo"},
+		{"file://localhost/srv/repo", RemoteURL{Scheme: "file", Path: "srv/repo"}, LocalFQDN, "srv/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseURL(%q) error = %v", tt.url, err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseURL(%q) = %+v, want %+v", tt.url, *got, tt.want)
+			}
+			if fqdn := got.FQDN(); fqdn != tt.wantFQDN {
+				t.Errorf("FQDN() = %q, want %q", fqdn, tt.wantFQDN)
+			}
+			if folders := got.Folders(); folders != tt.wantFolders {
+				t.Errorf("Folders() = %q, want %q", folders, tt.wantFolders)
+			}
+		})
+	}
+}
+
+func TestParseURLErrors(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr string
+	}{
+		{"", "url cannot be empty"},
+		{"   ", "url cannot be empty"},
+		{"repo", "invalid git url provided"},
+		{"/srv/git/repo.git", "invalid git url provided"},
+		{"git@host:", "invalid git url provided"},
+		{"git@host://repo.git", "invalid url"},
+		{"ftp://host/repo.git", "unsupported url scheme 'ftp'"},
+		{"https:///repo.git", "url has no host"},
+		{"file://server/srv/repo.git", "must point to a local path"},
+		{"https://host/", "url has no repository path"},
+		{"git@host:.git", "url has no repository path"},
+		{"https://host/group//repo.git", "is not a clean path"},
+		{"git@host:group/../repo.git", "is not a clean path"},
+		{"https://host:port/repo", "invalid url"},
+		{"-uid:repo.git", "url host '-uid' must not start with '-'"},
+		{"git@-oProxyCommand=x:repo.git", "invalid git url provided"},
+		{"git@-host:repo.git", "url host '-host' must not start with '-'"},
+		{"ssh://-oProxyCommand=x/repo.git", "must not start with '-'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseURL(tt.url)
+			if err == nil {
+				t.Fatalf("ParseURL(%q) = %+v, want an error", tt.url, *got)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseURL(%q) error = %q, want %q", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package repository
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON Schema of repositories files, editors use it for completion and checks
+//
+//go:embed schema.json
+var Schema []byte
+
+// Severities of problems
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is an issue of a repositories file found by Validate
+type Problem struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", p.File, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", p.File, p.Line, p.Column, p.Severity, p.Message)
+}
+
+// Position in yaml syntax and type errors
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// declared is a group or project together with the node it was read from
+type declared struct {
+	file string
+	node *yaml.Node
+}
+
+func (d declared) position() string {
+	return fmt.Sprintf("%s:%d:%d", d.file, d.node.Line, d.node.Column)
+}
+
+// validator collects the problems of the repositories files and the files they include
+type validator struct {
+	problems []Problem
+	visited  map[string]bool
+	groups   map[string]declared // Group name to its first declaration
+	projects map[string]declared // Project key to its first declaration
+	paths    map[string]declared // Path on disk to the first project cloned there
+}
+
+// Validate checks the repositories files and reports every problem found, unlike Load it doesn't
+// stop at the first one. Only failures to read a file are returned as an error.
+func Validate() ([]Problem, error) {
+	v := &validator{
+		visited:  map[string]bool{},
+		groups:   map[string]declared{},
+		projects: map[string]declared{},
+		paths:    map[string]declared{},
+	}
+
+	if _, err := ParseLayout(Layout); err != nil {
+		v.problems = append(v.problems, Problem{File: "--layout", Severity: SeverityError, Message: err.Error()})
+	}
+
+	for _, path := range filePaths() {
+		if err := v.file(path); err != nil {
+			return nil, err
+		}
+	}
+	return v.problems, nil
+}
+
+func (v *validator) report(file string, node *yaml.Node, severity, format string, args ...any) {
+	problem := Problem{File: file, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		problem.Line, problem.Column = node.Line, node.Column
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// file checks a repositories file and the files it includes
+func (v *validator) file(path string) error {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("error resolving path %s: %w", path, err)
+	}
+	if v.visited[absolute] {
+		v.report(path, nil, SeverityError, "file is loaded more than once")
+		return nil
+	}
+	v.visited[absolute] = true
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error opening template file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		v.reportError(path, err)
+		return nil
+	}
+
+	sequence, version, err := groupsNode(&root)
+	if err != nil {
+		v.reportError(path, err)
+		return nil
+	}
+	if sequence == nil {
+		v.report(path, nil, SeverityWarning, "file has no groups")
+		return nil
+	}
+	if version < CurrentVersion {
+		v.report(path, nil, SeverityWarning, "file uses version %d of the repositories format, upgrade it with 'aww migrate'", version)
+	}
+
+	for _, item := range sequence.Content {
+		if include := includeNode(item); include != nil {
+			if err := v.include(path, include); err != nil {
+				return err
+			}
+			continue
+		}
+		v.group(path, item)
+	}
+	return nil
+}
+
+// include checks the files of an include directive
+func (v *validator) include(path string, include *yaml.Node) error {
+	patterns, err := includePatterns(include)
+	if err != nil {
+		v.report(path, include, SeverityError, "%v", err)
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		matches, err := resolveInclude(pattern, path)
+		if err != nil {
+			v.report(path, include, SeverityError, "%v", err)
+			continue
+		}
+		for _, match := range matches {
+			if err := v.file(match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// group checks a group and its projects
+func (v *validator) group(path string, node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.report(path, node, SeverityError, "expected a group or an include directive")
+		return
+	}
+	v.keys(path, node, reflect.TypeOf(Group{}))
+
+	// Values of the wrong type are left empty, the rest of the group is still checked
+	group := &Group{}
+	if err := node.Decode(group); err != nil {
+		v.decodeError(path, node, err)
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return
+		}
+	}
+	group.file = path
+
+	nameKey, nameValue := lookup(node, "name")
+	switch {
+	case nameKey == nil || group.Name == "":
+		v.report(path, node, SeverityError, "group has no name")
+	default:
+		if first, ok := v.groups[group.Name]; ok {
+			v.report(path, nameValue, SeverityError, "duplicate group '%s', first declared at %s", group.Name, first.position())
+		} else {
+			v.groups[group.Name] = declared{file: path, node: nameValue}
+		}
+	}
+
+	if group.Layout != "" {
+		if _, err := ParseLayout(group.Layout); err != nil {
+			_, layout := lookup(node, "layout")
+			v.report(path, layout, SeverityError, "%v", err)
+		}
+	}
+
+	v.clone(path, node, group.Clone)
+
+	_, actions := lookup(node, "actions")
+	if actions != nil {
+		v.keys(path, actions, reflect.TypeOf(GroupActions{}))
+		if group.Actions != nil && group.Actions.Push != nil && *group.Actions.Push && group.Actions.Commit == "" &&
+			!slices.ContainsFunc(group.Projects, hasCommit) {
+			push, _ := lookup(actions, "push")
+			v.report(path, push, SeverityWarning, "push without a commit in the group or its projects only pushes existing commits")
+		}
+	}
+
+	_, projects := lookup(node, "projects")
+	if len(group.Projects) == 0 {
+		v.report(path, node, SeverityWarning, "group '%s' has no projects", group.Name)
+		return
+	}
+
+	link([]*Group{group})
+	for i, project := range group.Projects {
+		v.project(path, projects.Content[i], group, project)
+	}
+}
+
+// project checks a project of a group
+func (v *validator) project(path string, node *yaml.Node, group *Group, project *Project) {
+	if node.Kind != yaml.MappingNode {
+		v.report(path, node, SeverityError, "expected a project")
+		return
+	}
+	v.keys(path, node, reflect.TypeOf(Project{}))
+
+	_, url := lookup(node, "url")
+	if url == nil {
+		v.report(path, node, SeverityError, "project has no url")
+		return
+	}
+	if err := project.Decode(); err != nil {
+		v.report(path, url, SeverityError, "invalid url %s: %v", project.Url, err)
+		return
+	}
+
+	key := projectKey(project.Url)
+	_, layoutErr := ParseLayout(project.GetLayout())
+	if first, ok := v.projects[key]; ok {
+		v.report(path, url, SeverityError, "duplicate project %s, first declared at %s", project.Url, first.position())
+	} else if layoutErr == nil {
+		// Invalid layouts are reported once for their group
+		v.projects[key] = declared{file: path, node: url}
+		v.path(path, url, project)
+	}
+
+	v.clone(path, node, project.Clone)
+
+	_, actions := lookup(node, "actions")
+	if actions == nil {
+		return
+	}
+	v.keys(path, actions, reflect.TypeOf(ProjectActions{}))
+
+	groupCommit := group.Actions != nil && group.Actions.Commit != ""
+	if project.Actions != nil && project.Actions.Push != nil && *project.Actions.Push && !hasCommit(project) && !groupCommit {
+		push, _ := lookup(actions, "push")
+		v.report(path, push, SeverityWarning, "push without a commit in the project or its group only pushes existing commits")
+	}
+}
+
+// path reports projects cloned to the same path
+func (v *validator) path(path string, url *yaml.Node, project *Project) {
+	projectPath, err := project.ResolvePath()
+	if err != nil {
+		v.report(path, url, SeverityError, "%v", err)
+		return
+	}
+
+	if first, ok := v.paths[projectPath]; ok {
+		v.report(path, url, SeverityError, "project is cloned to %s like the project at %s", projectPath, first.position())
+		return
+	}
+	v.paths[projectPath] = declared{file: path, node: url}
+}
+
+// clone checks the clone options of a group or a project
+func (v *validator) clone(path string, node *yaml.Node, options *CloneOptions) {
+	_, clone := lookup(node, "clone")
+	if clone == nil {
+		return
+	}
+	v.keys(path, clone, reflect.TypeOf(CloneOptions{}))
+
+	if options == nil {
+		return
+	}
+	if err := options.Check(); err != nil {
+		v.report(path, clone, SeverityError, "%v", err)
+	}
+}
+
+// keys reports the keys of a mapping which aren't fields of the type
+func (v *validator) keys(path string, node *yaml.Node, typ reflect.Type) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	known := yamlFields(typ)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !slices.Contains(known, key.Value) {
+			v.report(path, key, SeverityError, "unknown key '%s' (available: %s)", key.Value, strings.Join(known, ", "))
+		}
+	}
+}
+
+// reportError reports an error mentioning its line, like the errors of yaml
+func (v *validator) reportError(path string, err error) {
+	problem := Problem{File: path, Severity: SeverityError, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+	if match := yamlLinePattern.FindStringSubmatch(err.Error()); match != nil {
+		problem.Line, _ = strconv.Atoi(match[1])
+		problem.Column = 1
+		problem.Message = strings.TrimPrefix(err.Error(), match[0])
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// decodeError reports a type error of a node, yaml reports every wrong value on its own line
+func (v *validator) decodeError(path string, node *yaml.Node, err error) {
+	typeError, ok := err.(*yaml.TypeError)
+	if !ok {
+		v.report(path, node, SeverityError, "%v", err)
+		return
+	}
+
+	for _, message := range typeError.Errors {
+		v.reportError(path, errors.New(message))
+	}
+}
+
+// yamlFields returns the keys of a struct in yaml files
+func yamlFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+func hasCommit(project *Project) bool {
+	return project.Actions != nil && project.Actions.Commit != ""
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := repositoriesFiles(t,
+		"repositories.yaml", `version: 2
+groups:
+  - name: team
+    root: /work
+    actions:
+      push: true
+    projects:
+      - url: git@example.com:team/api.git
+        path: shared
+      - url: git@example.com:team/web.git
+        path: shared
+        branch: main
+      - url: -example.com:team/cli.git
+  - name: empty
+  - include: teams/team.yaml
+`,
+		"teams/team.yaml", `version: 2
+groups:
+  - name: team
+    projects:
+      - url: https://example.com/team/api
+`,
+	)
+	file, included := filepath.Join(dir, "repositories.yaml"), filepath.Join(dir, "teams", "team.yaml")
+
+	problems, err := Validate()
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	want := []Problem{
+		{file, 6, 7, SeverityWarning, "push without a commit in the group or its projects only pushes existing commits"},
+		{file, 12, 9, SeverityError, "unknown key 'branch' (available: url, path, labels, actions, clone)"},
+		{file, 10, 14, SeverityError, "project is cloned to /work/shared like the project at " + file + ":8:14"},
+		{file, 13, 14, SeverityError, "invalid url -example.com:team/cli.git: url host '-example.com' must not start with '-'"},
+		{file, 14, 5, SeverityWarning, "group 'empty' has no projects"},
+		{included, 3, 11, SeverityError, "duplicate group 'team', first declared at " + file + ":3:11"},
+		{included, 5, 14, SeverityError, "duplicate project https://example.com/team/api, first declared at " + file + ":8:14"},
+	}
+	if !slices.Equal(problems, want) {
+		var got, wanted []string
+		for _, problem := range problems {
+			got = append(got, problem.String())
+		}
+		for _, problem := range want {
+			wanted = append(wanted, problem.String())
+		}
+		t.Errorf("Validate() problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wanted, "\n"))
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the newest version of the repositories format this binary understands.
+//
+//	1: bare list of groups
+//	2: document with a version and the list of groups
+const CurrentVersion = 2
+
+// migrations upgrade the content of a file by one version, migrations[i] from version i+1
+var migrations = []func(content []byte, root *yaml.Node) ([]byte, error){
+	addVersion,
+}
+
+// fileDocument is the document form of a repositories file
+type fileDocument struct {
+	Version int `yaml:"version"`
+	Groups  any `yaml:"groups"`
+}
+
+// Keys of the document form
+var documentKeys = []string{"version", "groups"}
+
+// groupsNode returns the list of groups of a parsed file and the version of its format, a bare
+// list is version 1. A nil list is returned for an empty file.
+func groupsNode(root *yaml.Node) (*yaml.Node, int, error) {
+	if len(root.Content) == 0 {
+		return nil, 1, nil
+	}
+
+	node := root.Content[0]
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return node, 1, nil
+	case yaml.MappingNode:
+	default:
+		return nil, 0, fmt.Errorf("line %d: expected a list of groups or a document with a version", node.Line)
+	}
+
+	_, versionNode := lookup(node, "version")
+	if versionNode == nil {
+		return nil, 0, fmt.Errorf("line %d: document has no version", node.Line)
+	}
+	var version int
+	if err := versionNode.Decode(&version); err != nil || version < 2 {
+		return nil, 0, fmt.Errorf("line %d: invalid version '%s'", versionNode.Line, versionNode.Value)
+	}
+	if version > CurrentVersion {
+		return nil, version, fmt.Errorf("the file uses version %d of the repositories format, this aww supports versions up to %d, please upgrade aww", version, CurrentVersion)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; !slices.Contains(documentKeys, key.Value) {
+			return nil, version, fmt.Errorf("line %d: unknown key '%s' (available: %s)", key.Line, key.Value, strings.Join(documentKeys, ", "))
+		}
+	}
+
+	_, groups := lookup(node, "groups")
+	switch {
+	case groups == nil || groups.Tag == "!!null":
+		return nil, version, nil
+	case groups.Kind != yaml.SequenceNode:
+		return nil, version, fmt.Errorf("line %d: expected a list of groups", groups.Line)
+	}
+	return groups, version, nil
+}
+
+// Migrate upgrades every file read by the last Load to the current version of the format.
+// Comments and formatting are kept, previous contents are backed up. It returns the upgraded files.
+func Migrate() ([]string, error) {
+	var migrated []string
+	for _, src := range sources {
+		if src.version >= CurrentVersion {
+			continue
+		}
+
+		content := src.content
+		for version := src.version; version < CurrentVersion; version++ {
+			var root yaml.Node
+			if err := yaml.Unmarshal(content, &root); err != nil {
+				return migrated, fmt.Errorf("error parsing template file %s: %w", src.path, err)
+			}
+			upgraded, err := migrations[version-1](content, &root)
+			if err != nil {
+				return migrated, fmt.Errorf("error migrating %s to version %d: %w", src.path, version+1, err)
+			}
+			content = upgraded
+		}
+
+		if err := replaceFile(src.path, src.content, content); err != nil {
+			return migrated, err
+		}
+		items, err := src.parse(content)
+		if err != nil {
+			return migrated, err
+		}
+		src.bind(items)
+		migrated = append(migrated, src.path)
+	}
+	return migrated, nil
+}
+
+// addVersion turns a bare list of groups into a document, the list stays where it is and
+// becomes the value of the groups key. Comments at the top of the file stay above the version.
+func addVersion(content []byte, root *yaml.Node) ([]byte, error) {
+	doc := newDocument(content)
+
+	// The header goes after leading comments and the document start marker
+	line := 0
+	for line < doc.lineCount() && (!doc.isContent(line) || strings.HasPrefix(doc.line(line), "---")) {
+		line++
+	}
+
+	header := "version: 2\ngroups:\n"
+	if len(root.Content) == 0 {
+		header = "version: 2\ngroups: []\n"
+	} else if sequence := root.Content[0]; sequence.Style&yaml.FlowStyle != 0 {
+		// Flow lists at column 1 can't be the value of a key, they are written again. The leading
+		// comments stay in the file, they aren't written again with the list.
+		list := *sequence
+		list.HeadComment = ""
+		text, err := render(fileDocument{Version: 2, Groups: &list})
+		if err != nil {
+			return nil, err
+		}
+		doc.replace(doc.lines[line], len(content), text)
+		return doc.apply()
+	}
+
+	doc.insertLines(line, header)
+	return doc.apply()
+}
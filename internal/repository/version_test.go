@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAddVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		want       string
+		wantGroups int
+	}{
+		{
+			name:       "bare list",
+			content:    "- name: team\n  projects:\n    - url: git@host:team/api.git\n",
+			want:       "version: 2\ngroups:\n- name: team\n  projects:\n    - url: git@host:team/api.git\n",
+			wantGroups: 1,
+		},
+		{
+			name:       "leading comments",
+			content:    "# Repositories of the team\n\n# Keep sorted\n- name: team # main group\n  projects: []\n",
+			want:       "# Repositories of the team\n\n# Keep sorted\nversion: 2\ngroups:\n- name: team # main group\n  projects: []\n",
+			wantGroups: 1,
+		},
+		{
+			name:       "document start marker",
+			content:    "# Repositories\n---\n- name: team\n  projects: []\n",
+			want:       "# Repositories\n---\nversion: 2\ngroups:\n- name: team\n  projects: []\n",
+			wantGroups: 1,
+		},
+		{
+			name:       "flow list",
+			content:    "# Repositories\n[{name: team, projects: [{url: 'git@host:team/api.git'}]}]\n",
+			want:       "# Repositories\nversion: 2\ngroups: [{name: team, projects: [{url: 'git@host:team/api.git'}]}]\n",
+			wantGroups: 1,
+		},
+		{
+			name:    "empty file",
+			content: "",
+			want:    "version: 2\ngroups: []\n",
+		},
+		{
+			name:    "comments only",
+			content: "# Nothing yet\n",
+			want:    "# Nothing yet\nversion: 2\ngroups: []\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.content), &root); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := addVersion([]byte(tt.content), &root)
+			if err != nil {
+				t.Fatalf("addVersion() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("addVersion():\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			// The result is a document of the current version with the same groups
+			var result yaml.Node
+			if err := yaml.Unmarshal(got, &result); err != nil {
+				t.Fatalf("addVersion() result doesn't parse: %v", err)
+			}
+			sequence, version, err := groupsNode(&result)
+			if err != nil || version != CurrentVersion {
+				t.Fatalf("groupsNode() of the result = version %d, %v", version, err)
+			}
+			groups := 0
+			if sequence != nil {
+				groups = len(sequence.Content)
+			}
+			if groups != tt.wantGroups {
+				t.Errorf("result has %d groups, want %d", groups, tt.wantGroups)
+			}
+		})
+	}
+}
+
+func TestMigrateIncludes(t *testing.T) {
+	current := "version: 2\ngroups:\n  - name: b\n    projects: []\n"
+	dir := repositoriesFiles(t,
+		"repositories.yaml", "- name: main\n  projects: []\n- include: teams/*.yaml\n",
+		"teams/a.yaml", "- name: a\n  projects: []\n",
+		"teams/b.yaml", current,
+	)
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	migrated, err := Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	want := []string{filepath.Join(dir, "repositories.yaml"), filepath.Join(dir, "teams", "a.yaml")}
+	if !slices.Equal(migrated, want) {
+		t.Errorf("Migrate() = %q, want %q", migrated, want)
+	}
+	for path, content := range map[string]string{
+		"repositories.yaml": "version: 2\ngroups:\n- name: main\n  projects: []\n- include: teams/*.yaml\n",
+		"teams/a.yaml":      "version: 2\ngroups:\n- name: a\n  projects: []\n",
+		"teams/b.yaml":      current,
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s after Migrate:\n%s\nwant:\n%s", path, got, content)
+		}
+	}
+
+	// The migrated files load again and have nothing left to migrate
+	groups, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v after Migrate", err)
+	}
+	if len(groups) != 3 {
+		t.Errorf("Load() = %d groups after Migrate, want 3", len(groups))
+	}
+	if migrated, err := Migrate(); err != nil || len(migrated) > 0 {
+		t.Errorf("second Migrate() = %q, %v, want nothing to migrate", migrated, err)
+	}
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+	repositoriesFiles(t, "repositories.yaml", "version: 3\ngroups: []\n")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "uses version 3 of the repositories format, this aww supports versions up to 2") {
+		t.Fatalf("Load() error = %v, want an error about the newer version", err)
+	}
+	if migrated, err := Migrate(); err != nil || len(migrated) > 0 {
+		t.Errorf("Migrate() = %q, %v, want nothing migrated", migrated, err)
+	}
+}
@@ -0,0 +1,266 @@
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Parse parses a selection expression, an empty expression selects everything.
+//
+//	expr      = or
+//	or        = and { "||" and }
+//	and       = unary { "&&" unary }
+//	unary     = "!" unary | "(" expr ")" | predicate
+//	predicate = field ( "=" | "!=" | "~" | "!~" ) value | "label:" value
+//
+// '=' matches a glob pattern, '~' a regular expression. Values are bare words or double quoted strings.
+// Example: group=backend && url~"payments" && !label:archived
+func Parse(expression string) (Selector, error) {
+	if strings.TrimSpace(expression) == "" {
+		return All{}, nil
+	}
+
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	selector, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.peek())
+	}
+
+	return selector, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q at position %d", t.value, t.pos+1)
+}
+
+// isWordRune reports whether r can be part of a bare word (field names, labels, globs)
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-/*?:@+[]{}^$\\", r)
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		two := ""
+		if i+1 < len(runes) {
+			two = string(runes[i : i+2])
+		}
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case two == "&&":
+			tokens = append(tokens, token{tokenAnd, two, i})
+			i += 2
+		case two == "||":
+			tokens = append(tokens, token{tokenOr, two, i})
+			i += 2
+		case two == "!=" || two == "!~":
+			tokens = append(tokens, token{tokenOp, two, i})
+			i += 2
+		case r == '=' || r == '~':
+			tokens = append(tokens, token{tokenOp, string(r), i})
+			i++
+		case r == '!':
+			tokens = append(tokens, token{tokenNot, "!", i})
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == '"':
+			var builder strings.Builder
+			start := i
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				builder.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, builder.String(), start})
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, string(runes[start:i]), start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i+1)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid selection: "+format, args...)
+}
+
+func (p *parser) or() (Selector, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Selector, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &and{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Selector, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenNot:
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &not{inner}, nil
+	case tokenLParen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf("expected ')' but got %s", closing)
+		}
+		return inner, nil
+	case tokenWord:
+		return p.predicate(t)
+	default:
+		return nil, p.errorf("expected a condition but got %s", t)
+	}
+}
+
+func (p *parser) predicate(fieldToken token) (Selector, error) {
+	// label:<value> shorthand, the value may contain ':' itself (e.g. label:team:payments)
+	if value, ok := strings.CutPrefix(fieldToken.value, "label:"); ok {
+		if value == "" {
+			// Quoted value (label:"needs review") or separated by spaces
+			valueToken := p.next()
+			if valueToken.kind != tokenWord && valueToken.kind != tokenString {
+				return nil, p.errorf("expected a label after %s but got %s", fieldToken, valueToken)
+			}
+			value = valueToken.value
+		}
+		return newPredicate("label", "=", value)
+	}
+
+	field := strings.ToLower(fieldToken.value)
+	if !slices.Contains(fields, field) {
+		return nil, p.errorf("unknown field %s (available: %s)", fieldToken, strings.Join(fields, ", "))
+	}
+
+	op := p.next()
+	if op.kind != tokenOp {
+		return nil, p.errorf("expected one of =, !=, ~, !~ after %s but got %s", fieldToken, op)
+	}
+
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, p.errorf("expected a value after %s but got %s", op, value)
+	}
+
+	return newPredicate(field, op.value, value.value)
+}
+
+func newPredicate(field, op, value string) (Selector, error) {
+	var pattern *regexp.Regexp
+	var err error
+
+	if strings.HasSuffix(op, "~") {
+		pattern, err = regexp.Compile(value)
+	} else {
+		pattern, err = Glob(value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid selection: bad pattern %q: %w", value, err)
+	}
+
+	return &predicate{field: field, op: op, value: value, pattern: pattern}, nil
+}
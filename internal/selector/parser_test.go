@@ -0,0 +1,137 @@
+package selector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string // Parsed selector, with explicit precedence
+	}{
+		{"", "true"},
+		{"   ", "true"},
+		{"group=backend", `group="backend"`},
+		{"GROUP=backend", `group="backend"`},
+		{`url~"payments"`, `url~"payments"`},
+		{"host!=github.com", `host!="github.com"`},
+		{`path!~"^archive/"`, `path!~"^archive/"`},
+		{"label:archived", `label="archived"`},
+		{"label:team:payments", `label="team:payments"`},
+		{`label:"needs review"`, `label="needs review"`},
+		{`url="quoted \"value\""`, `url="quoted \"value\""`},
+		{"!label:archived", `!label="archived"`},
+		{"!!label:archived", `!!label="archived"`},
+		{"group=a && group=b && group=c", `((group="a" && group="b") && group="c")`},
+		{"group=a || group=b && group=c", `(group="a" || (group="b" && group="c"))`},
+		{"(group=a || group=b) && group=c", `((group="a" || group="b") && group="c")`},
+		{"!(group=a || label:x)", `!(group="a" || label="x")`},
+		{`group=backend && url~"payments" && !label:archived`, `((group="backend" && url~"payments") && !label="archived")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			selector, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+			}
+			if got := selector.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{"name=backend", `unknown field "name" at position 1`},
+		{"group", "expected one of =, !=, ~, !~"},
+		{"group=", "expected a value after"},
+		{"group=a &&", "expected a condition but got end of expression"},
+		{"group=a group=b", `unexpected "group" at position 9`},
+		{"(group=a", "expected ')' but got end of expression"},
+		{"group=a)", `unexpected ")" at position 8`},
+		{`url="payments`, "unterminated string at position 5"},
+		{"group=a & group=b", `unexpected character '&' at position 9`},
+		{"url~(", `expected a value after "~"`},
+		{`url~"[a-"`, "bad pattern"},
+		{"label:", `expected a label after "label:" at position 1 but got end of expression`},
+		{"label: && group=a", `expected a label after "label:" at position 1 but got "&&" at position 8`},
+		{"(label:)", `expected a label after "label:" at position 2 but got ")" at position 8`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			selector, err := Parse(tt.expression)
+			if err == nil {
+				t.Fatalf("Parse(%q) = %s, want an error", tt.expression, selector)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %q, want %q", tt.expression, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	payments := &Target{
+		Group:   "backend",
+		Url:     "git@github.com:acme/payments-api.git",
+		FQDN:    "github.com",
+		Folders: "acme/payments-api",
+		Path:    "/home/user/aww/github.com/acme/payments-api",
+		Labels:  []string{"go", "team:payments"},
+	}
+	archive := &Target{
+		Group:   "legacy",
+		Url:     "https://gitlab.com/acme/old-site.git",
+		FQDN:    "gitlab.com",
+		Folders: "acme/old-site",
+		Path:    "/home/user/aww/gitlab.com/acme/old-site",
+		Labels:  []string{"archived"},
+	}
+
+	tests := []struct {
+		expression   string
+		wantPayments bool
+		wantArchive  bool
+	}{
+		{"", true, true},
+		{"group=backend", true, false},
+		{"group=back*", true, false},
+		{"group=backen?", true, false},
+		{"group=back", false, false},
+		{"group!=backend", false, true},
+		{`url~"payments"`, true, false},
+		{`url!~"^https://"`, true, false},
+		{"fqdn=*lab.com", false, true},
+		{"host=github.com", true, false},
+		{"folders=acme/*", true, true},
+		{"path=*/old-site", false, true},
+		{"label:archived", false, true},
+		{"label:team:*", true, false},
+		{"label!=go", false, true},
+		{"!label:archived", true, false},
+		{"group=backend || label:archived", true, true},
+		{"folders=acme/* && !(label:archived || label:go)", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			selector, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+			}
+			if got := selector.Match(payments); got != tt.wantPayments {
+				t.Errorf("%s matches payments = %v, want %v", selector, got, tt.wantPayments)
+			}
+			if got := selector.Match(archive); got != tt.wantArchive {
+				t.Errorf("%s matches archive = %v, want %v", selector, got, tt.wantArchive)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Target is a project as seen by selection expressions
+type Target struct {
+	Group   string
+	Url     string
+	FQDN    string
+	Folders string
+	Path    string
+	Labels  []string
+}
+
+// field returns the values of a field of the target, labels have many values
+func (t *Target) field(name string) []string {
+	switch name {
+	case "group":
+		return []string{t.Group}
+	case "url":
+		return []string{t.Url}
+	case "fqdn", "host":
+		return []string{t.FQDN}
+	case "folders":
+		return []string{t.Folders}
+	case "path":
+		return []string{t.Path}
+	case "label":
+		return t.Labels
+	default:
+		return nil
+	}
+}
+
+// Fields available in expressions
+var fields = []string{"group", "url", "fqdn", "host", "folders", "path", "label"}
+
+// Selector decides whether a project is selected
+type Selector interface {
+	Match(target *Target) bool
+	String() string
+}
+
+// All selects every project
+type All struct{}
+
+func (All) Match(*Target) bool { return true }
+func (All) String() string     { return "true" }
+
+type and struct{ left, right Selector }
+
+func (s *and) Match(t *Target) bool { return s.left.Match(t) && s.right.Match(t) }
+func (s *and) String() string       { return fmt.Sprintf("(%s && %s)", s.left, s.right) }
+
+type or struct{ left, right Selector }
+
+func (s *or) Match(t *Target) bool { return s.left.Match(t) || s.right.Match(t) }
+func (s *or) String() string       { return fmt.Sprintf("(%s || %s)", s.left, s.right) }
+
+type not struct{ inner Selector }
+
+func (s *not) Match(t *Target) bool { return !s.inner.Match(t) }
+func (s *not) String() string       { return fmt.Sprintf("!%s", s.inner) }
+
+// predicate compares a field with a glob ('=') or a regular expression ('~')
+type predicate struct {
+	field   string
+	op      string
+	value   string
+	pattern *regexp.Regexp
+}
+
+func (p *predicate) Match(t *Target) bool {
+	matched := false
+	for _, value := range t.field(p.field) {
+		if p.pattern.MatchString(value) {
+			matched = true
+			break
+		}
+	}
+
+	if strings.HasPrefix(p.op, "!") {
+		return !matched
+	}
+	return matched
+}
+
+func (p *predicate) String() string {
+	return fmt.Sprintf("%s%s%q", p.field, p.op, p.value)
+}
+
+// Glob compiles a glob pattern matching the whole value, '*' matches any characters
+// (including '/') and '?' matches a single character
+func Glob(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+
+	return regexp.Compile(builder.String())
+}
+
+// Exclude returns a selector rejecting projects whose url, path ("<fqdn>/<folders>") or group
+// matches any of the glob patterns
+func Exclude(patterns []string) (Selector, error) {
+	var selector Selector = All{}
+
+	for _, pattern := range patterns {
+		glob, err := Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+
+		var matchAny Selector = &predicate{field: "url", op: "=", value: pattern, pattern: glob}
+		for _, field := range []string{"path", "group"} {
+			matchAny = &or{matchAny, &predicate{field: field, op: "=", value: pattern, pattern: glob}}
+		}
+		selector = &and{selector, &not{matchAny}}
+	}
+
+	return selector, nil
+}
+
+// And combines selectors, a project has to match all of them
+func And(selectors ...Selector) Selector {
+	var result Selector = All{}
+	for _, s := range selectors {
+		if _, ok := s.(All); ok {
+			continue
+		}
+		if _, ok := result.(All); ok {
+			result = s
+			continue
+		}
+		result = &and{result, s}
+	}
+	return result
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"aww/cmd"
+	"aww/internal/backend"
+	"aww/internal/cache"
+	"aww/internal/config"
 	"aww/internal/repository"
 	"context"
 	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -33,15 +39,148 @@ func main() {
 				Value:       false,
 				Destination: &cmd.Debug,
 			},
+			&cli.IntFlag{
+				Name:        "jobs",
+				Aliases:     []string{"j"},
+				Usage:       "number of repositories processed concurrently",
+				Sources:     cli.EnvVars("AWW_JOBS"),
+				Value:       int64(runtime.NumCPU()),
+				Destination: &cmd.Jobs,
+			},
+			&cli.StringFlag{
+				Name:        "backend",
+				Usage:       "git backend: 'cli' runs git, 'go-git' answers read-only queries in-process",
+				Sources:     cli.EnvVars("AWW_BACKEND"),
+				Destination: &cmd.BackendName,
+			},
+			&cli.StringFlag{
+				Name:        "profile",
+				Usage:       "profile of the config file",
+				Sources:     cli.EnvVars("AWW_PROFILE"),
+				Destination: &cmd.Profile,
+			},
+			&cli.StringFlag{
+				Name:        "config",
+				Usage:       "config file (default: ~/.aww/config.yaml)",
+				Sources:     cli.EnvVars("AWW_CONFIG"),
+				Destination: &config.FilePath,
+			},
+			&cli.StringSliceFlag{
+				Name:        "file",
+				Aliases:     []string{"f"},
+				Usage:       "repositories files merged into one list of groups, repeatable (default: repositories_file of the config, ~/.aww/repositories.yaml)",
+				Sources:     cli.EnvVars("AWW_FILE"),
+				Destination: &repository.Files,
+			},
+			&cli.StringFlag{
+				Name:        "root",
+				Usage:       "root directory of cloned repositories, groups can override it with 'root'",
+				Sources:     cli.EnvVars("AWW_ROOT"),
+				Value:       repository.DestRepoPath,
+				Destination: &repository.DestRepoPath,
+			},
+			&cli.StringFlag{
+				Name:        "layout",
+				Usage:       "template of repository paths below the root ({{.Group}}, {{.FQDN}}, {{.Port}}, {{.Folders}}, {{.Namespace}}, {{.Repo}}), groups can override it with 'layout'",
+				Sources:     cli.EnvVars("AWW_LAYOUT"),
+				Value:       repository.Layout,
+				Destination: &repository.Layout,
+			},
+			&cli.StringFlag{
+				Name:        "remote",
+				Usage:       "remote used by fetch, pull and push (the default remote when empty)",
+				Sources:     cli.EnvVars("AWW_REMOTE"),
+				Destination: &cmd.Remote,
+			},
+			&cli.BoolFlag{
+				Name:        "cache",
+				Usage:       "clone through bare mirrors of the cache sharing the objects of clones of the same repository",
+				Sources:     cli.EnvVars("AWW_CACHE"),
+				Destination: &cmd.UseCache,
+			},
+			&cli.StringFlag{
+				Name:        "cache-dir",
+				Usage:       "folder of the mirrors of the cache (default: cache_dir of the config, ~/.aww/cache)",
+				Sources:     cli.EnvVars("AWW_CACHE_DIR"),
+				Destination: &cache.Path,
+			},
+			&cli.BoolFlag{
+				Name:        "dissociate",
+				Usage:       "copy the objects borrowed from the cache, so clones keep working without it",
+				Sources:     cli.EnvVars("AWW_DISSOCIATE"),
+				Destination: &cmd.Dissociate,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Usage:       "log output format: 'text' or 'json'",
+				Sources:     cli.EnvVars("AWW_OUTPUT"),
+				Value:       config.OutputText,
+				Destination: &cmd.Output,
+			},
+			&cli.DurationFlag{
+				Name:        "clone-timeout",
+				Usage:       "maximum duration of a single git clone (0 disables the timeout)",
+				Sources:     cli.EnvVars("AWW_CLONE_TIMEOUT"),
+				Value:       backend.Timeouts.Clone,
+				Destination: &backend.Timeouts.Clone,
+			},
+			&cli.DurationFlag{
+				Name:        "fetch-timeout",
+				Usage:       "maximum duration of a single git fetch (0 disables the timeout)",
+				Sources:     cli.EnvVars("AWW_FETCH_TIMEOUT"),
+				Value:       backend.Timeouts.Fetch,
+				Destination: &backend.Timeouts.Fetch,
+			},
+			&cli.DurationFlag{
+				Name:        "pull-timeout",
+				Usage:       "maximum duration of a single git pull (0 disables the timeout)",
+				Sources:     cli.EnvVars("AWW_PULL_TIMEOUT"),
+				Value:       backend.Timeouts.Pull,
+				Destination: &backend.Timeouts.Pull,
+			},
+			&cli.DurationFlag{
+				Name:        "push-timeout",
+				Usage:       "maximum duration of a single git push (0 disables the timeout)",
+				Sources:     cli.EnvVars("AWW_PUSH_TIMEOUT"),
+				Value:       backend.Timeouts.Push,
+				Destination: &backend.Timeouts.Push,
+			},
+			&cli.DurationFlag{
+				Name:        "local-timeout",
+				Usage:       "maximum duration of local git commands like status or commit (0 disables the timeout)",
+				Sources:     cli.EnvVars("AWW_LOCAL_TIMEOUT"),
+				Value:       backend.Timeouts.Local,
+				Destination: &backend.Timeouts.Local,
+			},
+			&cli.IntFlag{
+				Name:        "retries",
+				Usage:       "number of attempts of network git operations failing with transient errors",
+				Sources:     cli.EnvVars("AWW_RETRIES"),
+				Value:       backend.Retry.Attempts,
+				Destination: &backend.Retry.Attempts,
+			},
 		},
+		Before: cmd.Configure,
 		Commands: []*cli.Command{
 			cmd.Git(),
+			cmd.Repos(),
+			cmd.Validate(),
+			cmd.Migrate(),
+			cmd.Import(),
+			cmd.Cache(),
+			cmd.Config(),
 		},
 	}
 
+	// Cancel running git processes on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Run the application
-	if err := app.Run(context.Background(), os.Args); err != nil {
-		log.Fatal().Err(err).Msg("Application encountered an error")
+	if err := app.Run(ctx, os.Args); err != nil {
+		stop()
+		count := cmd.Report(err)
+		log.Fatal().Int("errors", count).Msg("Application encountered an error")
 	}
 
 }